@@ -7,6 +7,10 @@ import (
 	"syscall"
 
 	"github.com/Adeel56/quotebox/internal/app"
+	"github.com/Adeel56/quotebox/internal/budget"
+	"github.com/Adeel56/quotebox/internal/client"
+	"github.com/Adeel56/quotebox/internal/db"
+	"github.com/Adeel56/quotebox/internal/store"
 	"github.com/joho/godotenv"
 )
 
@@ -16,16 +20,32 @@ func main() {
 		log.Println("No .env file found, using environment variables")
 	}
 
+	// quotebox migrate up|down|status manages the schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+
 	// Validate required environment variables
-	requiredEnvVars := []string{"OPENROUTER_API_KEY"}
+	var requiredEnvVars []string
+	for _, provider := range client.ConfiguredProviders() {
+		if provider == client.ProviderOpenRouter {
+			requiredEnvVars = append(requiredEnvVars, "OPENROUTER_API_KEY")
+		}
+	}
 	for _, envVar := range requiredEnvVars {
 		if os.Getenv(envVar) == "" {
 			log.Fatalf("Required environment variable %s is not set", envVar)
 		}
 	}
 
+	// Initialize database
+	database, err := db.Connect()
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+
 	// Create and start server
-	server := app.NewServer()
+	server := app.NewServer(store.NewGormStore(database), budget.NewGormStore(database))
 
 	// Setup graceful shutdown
 	quit := make(chan os.Signal, 1)
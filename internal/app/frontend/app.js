@@ -72,8 +72,57 @@ function setupEventListeners() {
     });
 }
 
-// Generate quote
-async function generateQuote(tag) {
+// Generate quote, streaming it as it is written when the browser supports it
+function generateQuote(tag) {
+    if (!window.EventSource) {
+        return generateQuoteOnce(tag);
+    }
+
+    const btn = document.querySelector('.btn-primary');
+    btn.disabled = true;
+    btn.textContent = 'Generating...';
+
+    return new Promise((resolve) => {
+        let text = '';
+        const source = new EventSource(`/api/v1/quote/stream?tag=${encodeURIComponent(tag)}`);
+
+        const finish = () => {
+            source.close();
+            btn.disabled = false;
+            btn.textContent = 'Generate Quote';
+            resolve();
+        };
+
+        source.addEventListener('token', (event) => {
+            text += JSON.parse(event.data).text;
+            displayStreamingQuote(tag, text);
+        });
+
+        source.addEventListener('done', (event) => {
+            const quote = JSON.parse(event.data);
+            displayQuote(quote);
+            addToHistory(quote);
+            showNotification('Quote generated successfully!', 'success');
+            finish();
+        });
+
+        source.addEventListener('failed', (event) => {
+            const error = JSON.parse(event.data);
+            console.error('Error generating quote:', error);
+            showNotification(error.message || 'Failed to generate quote', 'error');
+            finish();
+        });
+
+        // Connection errors, e.g. an invalid tag rejected before streaming starts
+        source.onerror = () => {
+            showNotification('Failed to generate quote', 'error');
+            finish();
+        };
+    });
+}
+
+// Generate quote in a single request
+async function generateQuoteOnce(tag) {
     try {
         const btn = document.querySelector('.btn-primary');
         btn.disabled = true;
@@ -104,6 +153,19 @@ async function generateQuote(tag) {
     }
 }
 
+// Display a quote while it is still being streamed
+function displayStreamingQuote(tag, text) {
+    const display = document.getElementById('quoteDisplay');
+    display.innerHTML = `
+        <div class="quote-content">
+            <span class="quote-tag"></span>
+            <blockquote class="quote-text streaming"></blockquote>
+        </div>
+    `;
+    display.querySelector('.quote-tag').textContent = tag;
+    display.querySelector('.quote-text').textContent = `"${text}`;
+}
+
 // Display quote
 function displayQuote(quote) {
     const display = document.getElementById('quoteDisplay');
@@ -111,22 +173,25 @@ function displayQuote(quote) {
     const text = quote.quote || quote.quote_text || quote.text || 'No quote available';
     const author = quote.author || 'Unknown';
     const source = quote.source || '';
+    const explanation = quote.explanation || '';
     
+    // Model output is set as text so it can never inject markup
     display.innerHTML = `
         <div class="quote-content">
-            <span class="quote-tag">${tag}</span>
-            <blockquote class="quote-text">"${text}"</blockquote>
-            <div class="quote-author">— ${author}</div>
-            ${source ? `<div class="quote-source">${source}</div>` : ''}
+            <span class="quote-tag"></span>
+            <blockquote class="quote-text"></blockquote>
+            <div class="quote-author"></div>
+            ${explanation ? '<div class="quote-explanation"></div>' : ''}
+            ${source ? '<div class="quote-source"></div>' : ''}
             <div class="quote-actions">
-                <button class="btn-secondary" onclick="copyQuote('${text.replace(/'/g, "\\'")}', '${author.replace(/'/g, "\\'")}')">
+                <button class="btn-secondary quote-copy">
                     <svg width="16" height="16" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2">
                         <rect x="9" y="9" width="13" height="13" rx="2" ry="2"></rect>
                         <path d="M5 15H4a2 2 0 0 1-2-2V4a2 2 0 0 1 2-2h9a2 2 0 0 1 2 2v1"></path>
                     </svg>
                     Copy
                 </button>
-                <button class="btn-secondary" onclick="shareQuote('${text.replace(/'/g, "\\'")}', '${author.replace(/'/g, "\\'")}')">
+                <button class="btn-secondary quote-share">
                     <svg width="16" height="16" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2">
                         <circle cx="18" cy="5" r="3"></circle>
                         <circle cx="6" cy="12" r="3"></circle>
@@ -139,6 +204,17 @@ function displayQuote(quote) {
             </div>
         </div>
     `;
+    display.querySelector('.quote-tag').textContent = tag;
+    display.querySelector('.quote-text').textContent = `"${text}"`;
+    display.querySelector('.quote-author').textContent = `— ${author}`;
+    if (explanation) {
+        display.querySelector('.quote-explanation').textContent = explanation;
+    }
+    if (source) {
+        display.querySelector('.quote-source').textContent = source;
+    }
+    display.querySelector('.quote-copy').addEventListener('click', () => copyQuote(text, author));
+    display.querySelector('.quote-share').addEventListener('click', () => shareQuote(text, author));
 }
 
 // Add to history
@@ -151,9 +227,11 @@ function addToHistory(quote) {
     const text = quote.quote || quote.quote_text || quote.text || 'No quote available';
     
     item.innerHTML = `
-        <div class="tag">${tag}</div>
-        <div class="preview">${text}</div>
+        <div class="tag"></div>
+        <div class="preview"></div>
     `;
+    item.querySelector('.tag').textContent = tag;
+    item.querySelector('.preview').textContent = text;
     
     item.addEventListener('click', () => displayQuote(quote));
     
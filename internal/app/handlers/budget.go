@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Adeel56/quotebox/internal/budget"
+	"github.com/Adeel56/quotebox/internal/client"
+	"github.com/Adeel56/quotebox/internal/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// apiKeyHeader carries the API key identifying the consumer a request is billed to
+const apiKeyHeader = "X-API-Key"
+
+// budgetPeriodNames are the adjectives used for budget periods in error messages
+var budgetPeriodNames = map[string]string{
+	budget.PeriodDay:   "daily",
+	budget.PeriodMonth: "monthly",
+}
+
+// consumerID identifies the caller for per-consumer budgets: the consumer
+// whose API key is sent in the X-API-Key header, then the client IP.
+// Unauthenticated values such as the requestor field are never trusted.
+func (h *QuoteHandler) consumerID(c *gin.Context) string {
+	if h.Budget != nil {
+		if consumer, ok := h.Budget.Consumer(strings.TrimSpace(c.GetHeader(apiKeyHeader))); ok {
+			return "key:" + consumer
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// budgetedGenerator returns the generator to use for the consumer given the
+// remaining budget. When the budget is exhausted and no fallback applies it
+// writes the error response and returns nil.
+func (h *QuoteHandler) budgetedGenerator(ctx context.Context, c *gin.Context, consumer string) client.QuoteGenerator {
+	if h.Budget == nil {
+		return h.Generator
+	}
+
+	err := h.Budget.Check(ctx, consumer)
+	if err == nil {
+		return h.Generator
+	}
+
+	var exceeded *budget.ExceededError
+	if !errors.As(err, &exceeded) {
+		log.Printf("Error checking budget: %v", err)
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{
+			Error:   "budget_unavailable",
+			Message: "Unable to verify the quote budget. Please try again later.",
+		})
+		return nil
+	}
+
+	if h.BudgetFallback != nil {
+		log.Printf("Budget exceeded (%v), using fallback providers: consumer=%s", exceeded, consumer)
+		metrics.RecordBudgetExceeded(exceeded.Scope, budget.ActionFallback)
+		return h.BudgetFallback
+	}
+
+	log.Printf("Budget exceeded (%v), rejecting request: consumer=%s", exceeded, consumer)
+	metrics.RecordBudgetExceeded(exceeded.Scope, "rejected")
+
+	retryAfter := int(math.Ceil(time.Until(exceeded.ResetAt).Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+
+	// A consumer can wait for its own quota; the service itself is out of budget
+	status, code := http.StatusPaymentRequired, "budget_exceeded"
+	if exceeded.Scope == budget.ScopeConsumer {
+		status, code = http.StatusTooManyRequests, "consumer_budget_exceeded"
+	}
+	c.JSON(status, ErrorResponse{
+		Error:   code,
+		Message: fmt.Sprintf("The %s %s budget is exhausted. It resets at %s.", budgetPeriodNames[exceeded.Period], exceeded.Unit, exceeded.ResetAt.Format(time.RFC3339)),
+	})
+	return nil
+}
+
+// recordUsage adds the usage of a generation to the consumer's budgets.
+// The money is spent even if the client has gone away, so it is always recorded.
+func (h *QuoteHandler) recordUsage(consumer string, usage client.Usage, costUSD float64) {
+	if h.Budget == nil {
+		return
+	}
+
+	tokens := usage.TotalTokens
+	if tokens == 0 {
+		tokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	if err := h.Budget.Record(context.Background(), consumer, tokens, costUSD); err != nil {
+		log.Printf("Error recording budget usage: %v", err)
+	}
+}
+
+// recordSpent adds the usage of a failed generation to the consumer's budgets
+func (h *QuoteHandler) recordSpent(consumer string, err error) {
+	usage, costUSD := client.SpentUsage(err)
+	h.recordUsage(consumer, usage, costUSD)
+}
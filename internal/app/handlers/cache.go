@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/Adeel56/quotebox/internal/cache"
+	"github.com/Adeel56/quotebox/internal/client"
+	"github.com/Adeel56/quotebox/internal/metrics"
+	"github.com/Adeel56/quotebox/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+// Sources of a cache hit, also used as the quote_cache_hits_total label
+const (
+	cacheSourceMemory   = "memory"
+	cacheSourceDatabase = "database"
+)
+
+// cacheHeader reports whether a response was served from the cache
+const cacheHeader = "X-Cache"
+
+// storedCandidates bounds how many stored quotes are considered for reuse
+const storedCandidates = 20
+
+// QuoteCache serves repeated requests for the same tag and options without
+// calling a provider: from recently generated responses, and optionally from
+// stored quotes that have not been shown recently. Cached responses are checked
+// against the stored quotes, so edited and deleted quotes are not served stale.
+type QuoteCache struct {
+	Backend cache.Backend
+	// TTL is how long a response is served again; 0 disables the response cache
+	TTL time.Duration
+	// ReuseStored serves stored quotes not shown within ReuseAfter
+	ReuseStored bool
+	ReuseAfter  time.Duration
+	// Quotes is where stored quotes are reused from and cached ones checked against
+	Quotes store.QuoteStore
+}
+
+// NewQuoteCache creates a quote cache on the backend with the given settings,
+// reusing stored quotes from quotes
+func NewQuoteCache(backend cache.Backend, config cache.Config, quotes store.QuoteStore) *QuoteCache {
+	return &QuoteCache{
+		Backend:     backend,
+		TTL:         config.TTL,
+		ReuseStored: config.ReuseStored,
+		ReuseAfter:  config.ReuseAfter,
+		Quotes:      quotes,
+	}
+}
+
+// quoteCacheKey identifies the responses interchangeable for a request
+func quoteCacheKey(tag string, opts client.GenerateOptions) string {
+	fields := []string{tag, opts.Tone, opts.Length, opts.Language, opts.Audience, opts.Model}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x00")))
+	return "quote:response:" + hex.EncodeToString(sum[:])
+}
+
+// shownKey marks a quote as recently shown
+func shownKey(q QuoteResponse) string {
+	return "quote:shown:" + q.ID.String()
+}
+
+// Lookup returns a quote for the request without generating one, and the
+// source it came from. Cache errors are logged and count as misses.
+func (qc *QuoteCache) Lookup(ctx context.Context, tag string, opts client.GenerateOptions) (*QuoteResponse, string) {
+	key := quoteCacheKey(tag, opts)
+
+	if qc.TTL > 0 {
+		data, ok, err := qc.Backend.Get(ctx, key)
+		if err != nil {
+			log.Printf("Error reading quote cache: %v", err)
+		} else if ok {
+			var response QuoteResponse
+			if err := json.Unmarshal(data, &response); err != nil {
+				log.Printf("Warning: discarding invalid quote cache entry: %v", err)
+			} else if current := qc.current(ctx, tag, response); current != nil {
+				metrics.RecordCacheHit(cacheSourceMemory)
+				qc.markShown(ctx, *current)
+				return current, cacheSourceMemory
+			}
+		}
+	}
+
+	if qc.ReuseStored && qc.Quotes != nil {
+		if response := qc.storedQuote(ctx, tag, opts); response != nil {
+			metrics.RecordCacheHit(cacheSourceDatabase)
+			qc.Store(ctx, tag, opts, *response)
+			return response, cacheSourceDatabase
+		}
+	}
+
+	metrics.RecordCacheMiss()
+	return nil, ""
+}
+
+// current returns the stored version of a cached response, so edits are served,
+// or nil when the quote was deleted or retagged since it was cached. The cached
+// response is served when the store cannot be reached.
+func (qc *QuoteCache) current(ctx context.Context, tag string, cached QuoteResponse) *QuoteResponse {
+	if qc.Quotes == nil {
+		return &cached
+	}
+
+	quote, err := qc.Quotes.Get(ctx, cached.ID)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		log.Printf("Dropping cached quote %s, it was deleted", cached.ID)
+		return nil
+	case err != nil:
+		log.Printf("Error checking cached quote %s: %v", cached.ID, err)
+		return &cached
+	case quote.Tag != tag:
+		return nil
+	}
+	response := newQuoteResponse(*quote)
+	return &response
+}
+
+// Store caches a response served for the request and marks it as shown
+func (qc *QuoteCache) Store(ctx context.Context, tag string, opts client.GenerateOptions, response QuoteResponse) {
+	if qc.TTL > 0 {
+		data, err := json.Marshal(response)
+		if err == nil {
+			err = qc.Backend.Set(ctx, quoteCacheKey(tag, opts), data, qc.TTL)
+		}
+		if err != nil {
+			log.Printf("Error writing quote cache: %v", err)
+		}
+	}
+	qc.markShown(ctx, response)
+}
+
+// markShown records that a quote was served so it is not reused for a while
+func (qc *QuoteCache) markShown(ctx context.Context, response QuoteResponse) {
+	if !qc.ReuseStored {
+		return
+	}
+	if err := qc.Backend.Set(ctx, shownKey(response), []byte{1}, qc.ReuseAfter); err != nil {
+		log.Printf("Error writing quote cache: %v", err)
+	}
+}
+
+// storedQuote picks a random recent stored quote for the same tag and options
+// that has not been shown within ReuseAfter
+func (qc *QuoteCache) storedQuote(ctx context.Context, tag string, opts client.GenerateOptions) *QuoteResponse {
+	quotes, err := qc.Quotes.List(ctx, store.QuoteFilter{
+		Tag:        tag,
+		Tone:       opts.Tone,
+		Length:     opts.Length,
+		Audience:   opts.Audience,
+		Language:   opts.Language,
+		Model:      opts.Model,
+		MatchEmpty: true,
+		Limit:      storedCandidates,
+	})
+	if err != nil {
+		log.Printf("Error fetching stored quotes for reuse: %v", err)
+		return nil
+	}
+
+	var candidates []QuoteResponse
+	for _, quote := range quotes {
+		response := newQuoteResponse(quote)
+		_, shown, err := qc.Backend.Get(ctx, shownKey(response))
+		if err != nil {
+			log.Printf("Error reading quote cache: %v", err)
+			return nil
+		}
+		if !shown {
+			candidates = append(candidates, response)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	return &candidates[rand.Intn(len(candidates))]
+}
+
+// cachedQuote looks the request up in the cache unless caching is off or the
+// caller opted out, setting the X-Cache header when the cache is consulted
+func (h *QuoteHandler) cachedQuote(ctx context.Context, c *gin.Context, tag string, opts client.GenerateOptions, noCache bool) *QuoteResponse {
+	if h.Cache == nil || noCache {
+		return nil
+	}
+
+	response, source := h.Cache.Lookup(ctx, tag, opts)
+	if response == nil {
+		c.Header(cacheHeader, "MISS")
+		return nil
+	}
+
+	log.Printf("Quote served from %s cache: ID=%s, Tag=%s", source, response.ID, tag)
+	c.Header(cacheHeader, "HIT")
+	return response
+}
+
+// cacheQuote stores a newly generated quote for later requests. Requests that
+// bypassed the cache still refresh it.
+func (h *QuoteHandler) cacheQuote(tag string, opts client.GenerateOptions, response QuoteResponse) {
+	if h.Cache == nil {
+		return
+	}
+	h.Cache.Store(context.Background(), tag, opts, response)
+}
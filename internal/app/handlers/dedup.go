@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/Adeel56/quotebox/internal/client"
+	"github.com/Adeel56/quotebox/internal/dedup"
+	"github.com/Adeel56/quotebox/internal/metrics"
+	"github.com/Adeel56/quotebox/internal/models"
+	"github.com/Adeel56/quotebox/internal/store"
+	"github.com/google/uuid"
+)
+
+// deduplicate checks a generated quote against the quotes stored for its tag.
+// In regenerate mode it asks regenerate for another candidate while attempts
+// remain; regenerate returns nil when it has none. It returns the quote to
+// store and the stored quote it still duplicates, if any. A failed check is
+// logged and lets the quote through. Copies shared by coalesced requests are
+// never regenerated; they are stored as duplicates of the first request's quote.
+// Callers hold lockText for the quote until it is stored.
+func (h *QuoteHandler) deduplicate(ctx context.Context, tag string, result *client.GenerationResult, regenerate func() (*client.GenerationResult, error)) (*client.GenerationResult, *models.Quote, error) {
+	if h.Dedup == nil {
+		return result, nil, nil
+	}
+
+	for attempt := 0; ; attempt++ {
+		original, err := h.findDuplicate(ctx, tag, result.Text)
+		if err != nil {
+			log.Printf("Error checking for duplicate quotes: %v", err)
+			return result, nil, nil
+		}
+		metrics.RecordDuplicateCheck(tag, original != nil)
+		if original == nil {
+			return result, nil, nil
+		}
+
+		log.Printf("Generated quote for %s duplicates stored quote %s", tag, original.ID)
+		if h.Dedup.Config.Mode != dedup.ModeRegenerate || regenerate == nil || result.Shared || attempt >= h.Dedup.Config.MaxAttempts {
+			return result, original, nil
+		}
+
+		next, err := regenerate()
+		if err != nil {
+			return nil, nil, err
+		}
+		if next == nil {
+			return result, original, nil
+		}
+
+		// The discarded quote was still paid for
+		metrics.RecordUsage(result.Model, result.Usage.PromptTokens, result.Usage.CompletionTokens, result.CostUSD)
+		result = next
+	}
+}
+
+// findDuplicate returns the stored quote of the tag that the text repeats
+// exactly, or the recent one it nearly repeats, or nil
+func (h *QuoteHandler) findDuplicate(ctx context.Context, tag, text string) (*models.Quote, error) {
+	exact, err := h.Store.List(ctx, store.QuoteFilter{Tag: tag, TextHash: dedup.Hash(text), Oldest: true, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(exact) > 0 {
+		return &exact[0], nil
+	}
+	if h.Dedup.Config.Window == 0 {
+		return nil, nil
+	}
+
+	recent, err := h.Store.List(ctx, store.QuoteFilter{Tag: tag, Limit: h.Dedup.Config.Window})
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]dedup.Candidate, len(recent))
+	for i, quote := range recent {
+		candidates[i] = dedup.Candidate{Text: quote.QuoteText, Hash: quote.TextHash}
+	}
+	if i := h.Dedup.Find(text, candidates); i >= 0 {
+		return &recent[i], nil
+	}
+	return nil, nil
+}
+
+// duplicateRoot returns the ID of the first quote in a chain of duplicates
+func duplicateRoot(original *models.Quote) *uuid.UUID {
+	if original.DuplicateOf != nil {
+		return original.DuplicateOf
+	}
+	id := original.ID
+	return &id
+}
+
+// lockText holds the lock for quotes of the tag with the text until the
+// returned function is called, so that identical quotes generated at once are
+// checked and stored one after the other and only the first is stored as new
+func (h *QuoteHandler) lockText(tag, text string) func() {
+	if h.Dedup == nil {
+		return func() {}
+	}
+	return h.saving.lock(tag + "|" + dedup.Hash(text))
+}
+
+// textLocks hands out a lock per key, kept while anyone holds or waits for it
+type textLocks struct {
+	mu    sync.Mutex
+	locks map[string]*textLock
+}
+
+type textLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lock acquires the key's lock and returns the function releasing it
+func (l *textLocks) lock(key string) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*textLock)
+	}
+	held, ok := l.locks[key]
+	if !ok {
+		held = &textLock{}
+		l.locks[key] = held
+	}
+	held.refs++
+	l.mu.Unlock()
+
+	held.Lock()
+	return func() {
+		held.Unlock()
+		l.mu.Lock()
+		held.refs--
+		if held.refs == 0 {
+			delete(l.locks, key)
+		}
+		l.mu.Unlock()
+	}
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Adeel56/quotebox/internal/client"
+	"github.com/Adeel56/quotebox/internal/dedup"
+	"github.com/Adeel56/quotebox/internal/models"
+	"github.com/Adeel56/quotebox/internal/store"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxAuthorLength matches the size of the author column
+const maxAuthorLength = 255
+
+// UpdateQuoteRequest represents the request body for editing a quote; omitted fields are kept
+type UpdateQuoteRequest struct {
+	Quote *string `json:"quote"`
+	// Author is cleared when set to an empty string
+	Author *string `json:"author"`
+	Tag    *string `json:"tag"`
+}
+
+// GetQuote handles GET /api/v1/quotes/:id
+func (h *QuoteHandler) GetQuote(c *gin.Context) {
+	id, ok := quoteID(c)
+	if !ok {
+		return
+	}
+
+	quote, err := h.Store.Get(c.Request.Context(), id)
+	if err != nil {
+		storeError(c, id, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newQuoteResponse(*quote))
+}
+
+// UpdateQuote handles PATCH /api/v1/quotes/:id. Edited text must pass the
+// checks applied to generated quotes.
+func (h *QuoteHandler) UpdateQuote(c *gin.Context) {
+	id, ok := quoteID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateQuoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: fmt.Sprintf("Invalid JSON format: %v", err),
+		})
+		return
+	}
+	if req.Quote == nil && req.Author == nil && req.Tag == nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Provide at least one of quote, author and tag",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	quote, err := h.Store.Get(ctx, id)
+	if err != nil {
+		storeError(c, id, err)
+		return
+	}
+
+	if req.Quote != nil {
+		text := strings.TrimSpace(*req.Quote)
+		if text == "" {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_quote",
+				Message: "Quote cannot be empty",
+			})
+			return
+		}
+		if h.Validation != nil {
+			var rejection *client.RejectionError
+			if err := h.Validation.Validate(text); errors.As(err, &rejection) {
+				c.JSON(http.StatusBadRequest, ErrorResponse{
+					Error:   "invalid_quote",
+					Message: fmt.Sprintf("Quote was rejected: %s", strings.ReplaceAll(rejection.Reason, "_", " ")),
+				})
+				return
+			}
+		}
+		quote.QuoteText = text
+		quote.TextHash = dedup.Hash(text)
+	}
+
+	if req.Author != nil {
+		author := strings.TrimSpace(*req.Author)
+		if len(author) > maxAuthorLength {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_author",
+				Message: fmt.Sprintf("Author must be %d characters or less", maxAuthorLength),
+			})
+			return
+		}
+		quote.Author = nil
+		if author != "" {
+			quote.Author = &author
+		}
+	}
+
+	if req.Tag != nil {
+		tag, errResp := validateTag(*req.Tag)
+		if errResp != nil {
+			c.JSON(http.StatusBadRequest, errResp)
+			return
+		}
+		if errResp := h.screenTag(tag); errResp != nil {
+			c.JSON(http.StatusBadRequest, errResp)
+			return
+		}
+		quote.Tag = tag
+		quote.TagSource = models.GetTagSource(tag)
+	}
+
+	if errResp := h.screenQuote(ctx, *quote); errResp != nil {
+		errResp.Message = "The edited quote was rejected by the content filter."
+		c.JSON(http.StatusUnprocessableEntity, errResp)
+		return
+	}
+
+	if err := h.Store.Update(ctx, quote); err != nil {
+		storeError(c, id, err)
+		return
+	}
+
+	log.Printf("Quote updated: ID=%s, Tag=%s", quote.ID, quote.Tag)
+	c.JSON(http.StatusOK, newQuoteResponse(*quote))
+}
+
+// DeleteQuote handles DELETE /api/v1/quotes/:id. The quote is soft-deleted
+// and can be restored by an admin.
+func (h *QuoteHandler) DeleteQuote(c *gin.Context) {
+	id, ok := quoteID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.Store.Delete(c.Request.Context(), id); err != nil {
+		storeError(c, id, err)
+		return
+	}
+
+	log.Printf("Quote deleted: ID=%s", id)
+	c.Status(http.StatusNoContent)
+}
+
+// RestoreQuote handles POST /api/v1/quotes/:id/restore
+func (h *QuoteHandler) RestoreQuote(c *gin.Context) {
+	id, ok := quoteID(c)
+	if !ok {
+		return
+	}
+
+	quote, err := h.Store.Restore(c.Request.Context(), id)
+	if err != nil {
+		storeError(c, id, err)
+		return
+	}
+
+	log.Printf("Quote restored: ID=%s", id)
+	c.JSON(http.StatusOK, newQuoteResponse(*quote))
+}
+
+// quoteID parses the :id path parameter, responding with 400 when it is not a UUID
+func quoteID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Quote ID must be a UUID",
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// storeError responds to a failed store operation on a quote
+func storeError(c *gin.Context, id uuid.UUID, err error) {
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "quote_not_found",
+			Message: fmt.Sprintf("Quote %s not found", id),
+		})
+		return
+	}
+
+	log.Printf("Error accessing quote %s: %v", id, err)
+	c.JSON(http.StatusInternalServerError, ErrorResponse{
+		Error:   "database_error",
+		Message: "Failed to access quote",
+	})
+}
+
+// NotFound responds to requests for unknown API routes
+func NotFound(c *gin.Context) {
+	c.JSON(http.StatusNotFound, ErrorResponse{
+		Error:   "not_found",
+		Message: fmt.Sprintf("No route for %s %s", c.Request.Method, c.Request.URL.Path),
+	})
+}
+
+// RequireAdmin only lets requests carrying the admin token as a bearer token
+// through. With an empty token every request is refused.
+func RequireAdmin(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, ErrorResponse{
+				Error:   "admin_disabled",
+				Message: "Admin endpoints are disabled; set QUOTE_ADMIN_TOKEN to enable them",
+			})
+			return
+		}
+
+		provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{
+				Error:   "unauthorized",
+				Message: "A valid admin token is required",
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"log"
+
+	"github.com/Adeel56/quotebox/internal/metrics"
+	"github.com/Adeel56/quotebox/internal/models"
+	"github.com/Adeel56/quotebox/internal/moderation"
+)
+
+// screenTag checks a custom tag against the moderation input rules
+func (h *QuoteHandler) screenTag(tag string) *ErrorResponse {
+	if h.Moderation == nil || models.IsValidTag(tag) {
+		return nil
+	}
+
+	violation := h.Moderation.CheckInput(tag)
+	if violation == nil {
+		return nil
+	}
+
+	log.Printf("Custom tag blocked by moderation rule %s (%s): %q", violation.Rule, violation.Category, tag)
+	metrics.RecordModerationBlocked(violation.Target, violation.Category)
+	return &ErrorResponse{
+		Error:    "tag_rejected",
+		Message:  "The tag was rejected by the content filter. Please choose another tag.",
+		Rule:     violation.Rule,
+		Category: violation.Category,
+	}
+}
+
+// screenQuote checks a generated quote and its author against the moderation
+// output rules. When the moderation service fails the quote is allowed.
+func (h *QuoteHandler) screenQuote(ctx context.Context, quote models.Quote) *ErrorResponse {
+	if h.Moderation == nil {
+		return nil
+	}
+
+	text := quote.QuoteText
+	if quote.Author != nil {
+		text += "\n" + *quote.Author
+	}
+
+	violation, err := h.Moderation.CheckOutput(ctx, text)
+	if err != nil {
+		log.Printf("Warning: moderation check failed, allowing quote: %v", err)
+		return nil
+	}
+	if violation == nil {
+		return nil
+	}
+
+	log.Printf("Generated quote blocked by moderation rule %s (%s): tag=%s", violation.Rule, violation.Category, quote.Tag)
+	metrics.RecordModerationBlocked(moderation.TargetOutput, violation.Category)
+	return &ErrorResponse{
+		Error:    "quote_rejected",
+		Message:  "The generated quote was rejected by the content filter. Please try again or choose another tag.",
+		Rule:     violation.Rule,
+		Category: violation.Category,
+	}
+}
@@ -1,30 +1,64 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Adeel56/quotebox/internal/budget"
 	"github.com/Adeel56/quotebox/internal/client"
-	"github.com/Adeel56/quotebox/internal/db"
+	"github.com/Adeel56/quotebox/internal/dedup"
 	"github.com/Adeel56/quotebox/internal/metrics"
 	"github.com/Adeel56/quotebox/internal/models"
+	"github.com/Adeel56/quotebox/internal/moderation"
+	"github.com/Adeel56/quotebox/internal/pool"
+	"github.com/Adeel56/quotebox/internal/store"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 // QuoteHandler handles quote-related requests
 type QuoteHandler struct {
-	OpenRouterClient *client.OpenRouterClient
+	Generator client.QuoteGenerator
+	// Store keeps the generated quotes
+	Store store.QuoteStore
+	// Models lists the models callers may request; nil allows only the default
+	Models *client.ModelAllowList
+	// Budget enforces spending limits when set
+	Budget *budget.Tracker
+	// BudgetFallback serves requests over budget; when nil they are rejected
+	BudgetFallback client.QuoteGenerator
+	// Cache serves repeated requests without generating when set
+	Cache *QuoteCache
+	// Dedup checks new quotes against stored ones when set
+	Dedup *dedup.Detector
+	// Moderation screens custom tags and generated quotes when set
+	Moderation *moderation.Filter
+	// Validation checks edited quotes against the limits of generated ones when set
+	Validation *client.Pipeline
+	// Pool holds pre-generated quotes for the preset tags when set
+	Pool *pool.Pool
+	// GenerationTimeout bounds each generation; 0 leaves only the client's own deadline
+	GenerationTimeout time.Duration
+
+	// saving serializes the duplicate check and the save of identical quotes
+	saving textLocks
 }
 
+// statusClientClosedRequest is the non-standard status used when the client disconnects
+const statusClientClosedRequest = 499
+
 // NewQuoteHandler creates a new quote handler
-func NewQuoteHandler(openRouterClient *client.OpenRouterClient) *QuoteHandler {
+func NewQuoteHandler(generator client.QuoteGenerator, quotes store.QuoteStore) *QuoteHandler {
 	return &QuoteHandler{
-		OpenRouterClient: openRouterClient,
+		Generator: generator,
+		Store:     quotes,
 	}
 }
 
@@ -32,22 +66,48 @@ func NewQuoteHandler(openRouterClient *client.OpenRouterClient) *QuoteHandler {
 type CreateQuoteRequest struct {
 	Tag       string `json:"tag" binding:"required"`
 	Requestor string `json:"requestor"`
+	QuoteOptions
+}
+
+// QuoteOptions holds the optional generation settings of a quote request
+type QuoteOptions struct {
+	Tone     string `json:"tone" form:"tone"`
+	Length   string `json:"length" form:"length"`
+	Language string `json:"language" form:"language"`
+	Audience string `json:"audience" form:"audience"`
+	Model    string `json:"model" form:"model"`
+	// NoCache always generates a new quote instead of serving a cached one
+	NoCache bool `json:"no_cache" form:"no_cache"`
 }
 
 // QuoteResponse represents the response for a quote
 type QuoteResponse struct {
-	ID        uuid.UUID `json:"id"`
-	Tag       string    `json:"tag"`
-	Quote     string    `json:"quote"`
-	Author    *string   `json:"author,omitempty"`
-	Source    string    `json:"source"`
-	CreatedAt time.Time `json:"created_at"`
+	ID               uuid.UUID  `json:"id"`
+	Tag              string     `json:"tag"`
+	Quote            string     `json:"quote"`
+	Author           *string    `json:"author,omitempty"`
+	Language         *string    `json:"language,omitempty"`
+	Explanation      *string    `json:"explanation,omitempty"`
+	Source           string     `json:"source"`
+	Model            string     `json:"model,omitempty"`
+	PromptTokens     int        `json:"prompt_tokens,omitempty"`
+	CompletionTokens int        `json:"completion_tokens,omitempty"`
+	CostUSD          float64    `json:"cost_usd,omitempty"`
+	PromptVersion    string     `json:"prompt_version,omitempty"`
+	Tone             string     `json:"tone,omitempty"`
+	Length           string     `json:"length,omitempty"`
+	Audience         string     `json:"audience,omitempty"`
+	DuplicateOf      *uuid.UUID `json:"duplicate_of,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
 }
 
 // ErrorResponse represents an error response
 type ErrorResponse struct {
 	Error   string `json:"error"`
 	Message string `json:"message,omitempty"`
+	// Rule and Category identify the moderation rule that rejected a request
+	Rule     string `json:"rule,omitempty"`
+	Category string `json:"category,omitempty"`
 }
 
 // CreateQuote handles POST /api/v1/quote
@@ -64,64 +124,112 @@ func (h *QuoteHandler) CreateQuote(c *gin.Context) {
 	}
 
 	// Validate tag
-	req.Tag = strings.TrimSpace(req.Tag)
-	if req.Tag == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error:   "invalid_tag",
-			Message: "Tag cannot be empty",
-		})
+	tag, errResp := validateTag(req.Tag)
+	if errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
 		return
 	}
+	req.Tag = tag
 
-	if len(req.Tag) > 50 {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error:   "invalid_tag",
-			Message: "Tag must be 50 characters or less",
+	if errResp := h.screenTag(req.Tag); errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	opts, errResp := h.validateOptions(req.QuoteOptions)
+	if errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	// Serve a pre-generated quote when the pool has one
+	if entry, ok := h.pooledQuote(req.Tag, opts); ok {
+		unlock := h.lockText(req.Tag, entry.Result.Text)
+		defer unlock()
+		result, original, _ := h.deduplicate(c.Request.Context(), req.Tag, entry.Result, func() (*client.GenerationResult, error) {
+			if next, ok := h.Pool.Take(req.Tag); ok {
+				return next.Result, nil
+			}
+			return nil, nil
 		})
+		h.saveQuote(c, req.Tag, opts, h.newQuote(c, req.Tag, opts, result, entry.Latency), original)
 		return
 	}
 
 	// Record start time
 	startTime := time.Now()
 
-	// Generate quote from OpenRouter
-	quoteText, err := h.OpenRouterClient.GenerateQuote(req.Tag)
-	if err != nil {
-		log.Printf("Error generating quote: %v", err)
-		metrics.RecordQuoteError()
-		c.JSON(http.StatusServiceUnavailable, ErrorResponse{
-			Error:   "quote_generation_failed",
-			Message: "Failed to generate quote. Please try again later.",
-		})
+	ctx, cancel := h.generationContext(c)
+	defer cancel()
+
+	if cached := h.cachedQuote(ctx, c, req.Tag, opts, req.NoCache); cached != nil {
+		c.JSON(http.StatusOK, cached)
 		return
 	}
 
-	// Calculate latency
-	latency := time.Since(startTime)
-	latencyMs := int(latency.Milliseconds())
+	consumer := h.consumerID(c)
+	generator := h.budgetedGenerator(ctx, c, consumer)
+	if generator == nil {
+		return
+	}
 
-	// Record metrics
-	metrics.RecordQuoteFetched(req.Tag)
-	metrics.RecordLatency(latency.Seconds())
+	// Generate quote from the configured provider
+	generate := func() (*client.GenerationResult, error) {
+		result, err := generator.GenerateQuote(ctx, req.Tag, opts)
+		if err != nil {
+			h.recordSpent(consumer, err)
+			return nil, err
+		}
+		h.recordUsage(consumer, result.Usage, result.CostUSD)
+		return result, nil
+	}
+	result, err := generate()
+	if err == nil {
+		unlock := h.lockText(req.Tag, result.Text)
+		defer unlock()
+		var original *models.Quote
+		if result, original, err = h.deduplicate(ctx, req.Tag, result, generate); err == nil {
+			h.saveQuote(c, req.Tag, opts, h.newQuote(c, req.Tag, opts, result, time.Since(startTime)), original)
+			return
+		}
+	}
+
+	status, errResp := h.generationError(req.Tag, err)
+	if errResp == nil {
+		c.AbortWithStatus(status)
+		return
+	}
+	c.JSON(status, errResp)
+}
 
-	// Determine tag source
-	tagSource := models.GetTagSource(req.Tag)
+// pooledQuote takes a pre-generated quote for requests without options
+func (h *QuoteHandler) pooledQuote(tag string, opts client.GenerateOptions) (pool.Entry, bool) {
+	if h.Pool == nil || opts != (client.GenerateOptions{}) {
+		return pool.Entry{}, false
+	}
+	return h.Pool.Take(tag)
+}
 
-	// Create quote record
-	quote := models.Quote{
-		Tag:       req.Tag,
-		TagSource: tagSource,
-		QuoteText: quoteText,
-		Author:    nil, // OpenRouter doesn't typically return author
-		Source:    "openrouter",
-		CreatedAt: time.Now(),
-		LatencyMs: latencyMs,
-		ClientIP:  c.ClientIP(),
-		UserAgent: c.GetHeader("User-Agent"),
+// saveQuote stores a new quote and responds with it. A quote duplicating
+// original is stored with a reference to it, or not at all when duplicates
+// are answered with the stored quote.
+func (h *QuoteHandler) saveQuote(c *gin.Context, tag string, opts client.GenerateOptions, quote models.Quote, original *models.Quote) {
+	if original != nil {
+		if h.Dedup.Config.Mode == dedup.ModeReuse {
+			log.Printf("Serving stored quote %s instead of its duplicate: Tag=%s", original.ID, tag)
+			c.JSON(http.StatusOK, newQuoteResponse(*original))
+			return
+		}
+		quote.DuplicateOf = duplicateRoot(original)
+	}
+
+	if errResp := h.screenQuote(c.Request.Context(), quote); errResp != nil {
+		c.JSON(http.StatusUnprocessableEntity, errResp)
+		return
 	}
 
 	// Save to database
-	if err := db.DB.Create(&quote).Error; err != nil {
+	if err := h.Store.Create(c.Request.Context(), &quote); err != nil {
 		log.Printf("Error saving quote to database: %v", err)
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error:   "database_error",
@@ -132,15 +240,188 @@ func (h *QuoteHandler) CreateQuote(c *gin.Context) {
 
 	log.Printf("Quote created successfully: ID=%s, Tag=%s, Latency=%dms", quote.ID, quote.Tag, quote.LatencyMs)
 
+	response := newQuoteResponse(quote)
+	h.cacheQuote(tag, opts, response)
+
 	// Return response
-	c.JSON(http.StatusOK, QuoteResponse{
-		ID:        quote.ID,
-		Tag:       quote.Tag,
-		Quote:     quote.QuoteText,
-		Author:    quote.Author,
-		Source:    quote.Source,
-		CreatedAt: quote.CreatedAt,
-	})
+	c.JSON(http.StatusOK, response)
+}
+
+// validateTag trims the tag and checks it is usable
+func validateTag(tag string) (string, *ErrorResponse) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return "", &ErrorResponse{
+			Error:   "invalid_tag",
+			Message: "Tag cannot be empty",
+		}
+	}
+
+	if len(tag) > 50 {
+		return "", &ErrorResponse{
+			Error:   "invalid_tag",
+			Message: "Tag must be 50 characters or less",
+		}
+	}
+
+	return tag, nil
+}
+
+// validateOptions normalises the optional generation settings and checks them against the allow-lists
+func (h *QuoteHandler) validateOptions(options QuoteOptions) (client.GenerateOptions, *ErrorResponse) {
+	opts := client.GenerateOptions{
+		Tone:     strings.ToLower(strings.TrimSpace(options.Tone)),
+		Length:   strings.ToLower(strings.TrimSpace(options.Length)),
+		Language: strings.ToLower(strings.TrimSpace(options.Language)),
+		Audience: strings.ToLower(strings.TrimSpace(options.Audience)),
+		Model:    strings.TrimSpace(options.Model),
+	}
+
+	invalid := func(name string, allowed []string) *ErrorResponse {
+		return &ErrorResponse{
+			Error:   "invalid_option",
+			Message: fmt.Sprintf("%s must be one of: %s", name, strings.Join(allowed, ", ")),
+		}
+	}
+
+	if opts.Tone != "" && !models.IsValidOption(opts.Tone, models.ValidTones) {
+		return opts, invalid("tone", models.ValidTones)
+	}
+	if opts.Length != "" && !models.IsValidOption(opts.Length, models.ValidLengths) {
+		return opts, invalid("length", models.ValidLengths)
+	}
+	if opts.Language != "" && !models.IsValidLanguage(opts.Language) {
+		return opts, invalid("language", supportedLanguages())
+	}
+	if opts.Audience != "" && !models.IsValidOption(opts.Audience, models.ValidAudiences) {
+		return opts, invalid("audience", models.ValidAudiences)
+	}
+	if opts.Model != "" && (h.Models == nil || !h.Models.Allowed(opts.Model)) {
+		var allowed []string
+		if h.Models != nil {
+			allowed = h.Models.IDs()
+		}
+		return opts, &ErrorResponse{
+			Error:   "invalid_model",
+			Message: fmt.Sprintf("model must be one of: %s", strings.Join(allowed, ", ")),
+		}
+	}
+
+	return opts, nil
+}
+
+// supportedLanguages returns the sorted supported language codes
+func supportedLanguages() []string {
+	codes := make([]string, 0, len(models.Languages))
+	for code := range models.Languages {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// generationContext returns a context that ends when the client disconnects or the deadline passes
+func (h *QuoteHandler) generationContext(c *gin.Context) (context.Context, context.CancelFunc) {
+	if h.GenerationTimeout > 0 {
+		return context.WithTimeout(c.Request.Context(), h.GenerationTimeout)
+	}
+	return context.WithCancel(c.Request.Context())
+}
+
+// generationError records a failed generation and maps it to a status and response.
+// The response is nil when the client is gone and nothing should be written.
+func (h *QuoteHandler) generationError(tag string, err error) (int, *ErrorResponse) {
+	switch {
+	case errors.Is(err, context.Canceled):
+		log.Printf("Quote generation cancelled by client: tag=%s", tag)
+		metrics.RecordQuoteCancelled()
+		return statusClientClosedRequest, nil
+	case errors.Is(err, context.DeadlineExceeded):
+		log.Printf("Quote generation timed out after %s: tag=%s", h.GenerationTimeout, tag)
+		metrics.RecordQuoteError()
+		return http.StatusGatewayTimeout, &ErrorResponse{
+			Error:   "quote_generation_timeout",
+			Message: "Quote generation took too long. Please try again later.",
+		}
+	default:
+		log.Printf("Error generating quote: %v", err)
+		metrics.RecordQuoteError()
+		return http.StatusServiceUnavailable, &ErrorResponse{
+			Error:   "quote_generation_failed",
+			Message: "Failed to generate quote. Please try again later.",
+		}
+	}
+}
+
+// newQuote records metrics for a successful generation and builds its quote record
+func (h *QuoteHandler) newQuote(c *gin.Context, tag string, opts client.GenerateOptions, result *client.GenerationResult, latency time.Duration) models.Quote {
+	// Record metrics
+	metrics.RecordQuoteFetched(tag)
+	metrics.RecordQuoteModel(result.Source, result.Model)
+	metrics.RecordUsage(result.Model, result.Usage.PromptTokens, result.Usage.CompletionTokens, result.CostUSD)
+	metrics.RecordLatency(latency.Seconds())
+
+	return models.Quote{
+		Tag:              tag,
+		TagSource:        models.GetTagSource(tag),
+		QuoteText:        result.Text,
+		Author:           result.Author,
+		Language:         quoteLanguage(opts, result),
+		Explanation:      result.Explanation,
+		Source:           result.Source,
+		Model:            result.Model,
+		PromptTokens:     result.Usage.PromptTokens,
+		CompletionTokens: result.Usage.CompletionTokens,
+		TotalTokens:      result.Usage.TotalTokens,
+		CostUSD:          result.CostUSD,
+		PromptVersion:    result.PromptVersion,
+		Tone:             opts.Tone,
+		Length:           opts.Length,
+		Audience:         opts.Audience,
+		TextHash:         dedup.Hash(result.Text),
+		CreatedAt:        time.Now(),
+		LatencyMs:        int(latency.Milliseconds()),
+		ClientIP:         c.ClientIP(),
+		UserAgent:        c.GetHeader("User-Agent"),
+	}
+}
+
+// quoteLanguage returns the requested language, like the other stored options,
+// when the provider answered in it, or else the language the provider reported
+func quoteLanguage(opts client.GenerateOptions, result *client.GenerationResult) *string {
+	if opts.Language == "" || result.Language == nil {
+		return result.Language
+	}
+	// Reported languages may carry a region, e.g. "pt-BR"
+	primary, _, _ := strings.Cut(*result.Language, "-")
+	if !strings.EqualFold(primary, opts.Language) {
+		return result.Language
+	}
+	language := opts.Language
+	return &language
+}
+
+// newQuoteResponse converts a quote record to its API representation
+func newQuoteResponse(q models.Quote) QuoteResponse {
+	return QuoteResponse{
+		ID:               q.ID,
+		Tag:              q.Tag,
+		Quote:            q.QuoteText,
+		Author:           q.Author,
+		Language:         q.Language,
+		Explanation:      q.Explanation,
+		Source:           q.Source,
+		Model:            q.Model,
+		PromptTokens:     q.PromptTokens,
+		CompletionTokens: q.CompletionTokens,
+		CostUSD:          q.CostUSD,
+		PromptVersion:    q.PromptVersion,
+		Tone:             q.Tone,
+		Length:           q.Length,
+		Audience:         q.Audience,
+		DuplicateOf:      q.DuplicateOf,
+		CreatedAt:        q.CreatedAt,
+	}
 }
 
 // GetQuotes handles GET /api/v1/quotes
@@ -156,14 +437,17 @@ func (h *QuoteHandler) GetQuotes(c *gin.Context) {
 		limit = 100
 	}
 
-	query := db.DB.Order("created_at DESC").Limit(limit)
-
-	if tag != "" {
-		query = query.Where("tag = ?", tag)
-	}
-
-	var quotes []models.Quote
-	if err := query.Find(&quotes).Error; err != nil {
+	quotes, err := h.Store.List(c.Request.Context(), store.QuoteFilter{
+		Tag:           tag,
+		Model:         c.Query("model"),
+		PromptVersion: c.Query("prompt_version"),
+		Tone:          c.Query("tone"),
+		Length:        c.Query("length"),
+		Language:      c.Query("language"),
+		Audience:      c.Query("audience"),
+		Limit:         limit,
+	})
+	if err != nil {
 		log.Printf("Error fetching quotes: %v", err)
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error:   "database_error",
@@ -175,14 +459,7 @@ func (h *QuoteHandler) GetQuotes(c *gin.Context) {
 	// Convert to response format
 	responses := make([]QuoteResponse, len(quotes))
 	for i, q := range quotes {
-		responses[i] = QuoteResponse{
-			ID:        q.ID,
-			Tag:       q.Tag,
-			Quote:     q.QuoteText,
-			Author:    q.Author,
-			Source:    q.Source,
-			CreatedAt: q.CreatedAt,
-		}
+		responses[i] = newQuoteResponse(q)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -191,6 +468,21 @@ func (h *QuoteHandler) GetQuotes(c *gin.Context) {
 	})
 }
 
+// GetModels handles GET /api/v1/models
+func (h *QuoteHandler) GetModels(c *gin.Context) {
+	if h.Models == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"models": []client.ModelInfo{},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"models":  h.Models.Models(),
+		"default": h.Models.Default(),
+	})
+}
+
 // GetTags handles GET /api/v1/tags
 func (h *QuoteHandler) GetTags(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Adeel56/quotebox/internal/client"
+	"github.com/Adeel56/quotebox/internal/dedup"
+	"github.com/gin-gonic/gin"
+)
+
+// StreamQuote handles GET /api/v1/quote/stream?tag=<tag>, accepting the
+// QuoteOptions as further query parameters. A cached quote is sent as a single
+// "token" event.
+//
+// The quote is sent as server-sent events: a "token" event for each chunk of
+// text as it is generated, then a "done" event carrying the saved QuoteResponse,
+// or a "failed" event carrying an ErrorResponse.
+func (h *QuoteHandler) StreamQuote(c *gin.Context) {
+	tag, errResp := validateTag(c.Query("tag"))
+	if errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	if errResp := h.screenTag(tag); errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	var options QuoteOptions
+	if err := c.ShouldBindQuery(&options); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: fmt.Sprintf("Invalid query parameters: %v", err),
+		})
+		return
+	}
+	opts, errResp := h.validateOptions(options)
+	if errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	// Record start time
+	startTime := time.Now()
+
+	ctx, cancel := h.generationContext(c)
+	defer cancel()
+
+	cached := h.cachedQuote(ctx, c, tag, opts, options.NoCache)
+
+	var consumer string
+	var generator client.QuoteGenerator
+	if cached == nil {
+		consumer = h.consumerID(c)
+		if generator = h.budgetedGenerator(ctx, c, consumer); generator == nil {
+			return
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	// A cached quote arrives in a single chunk
+	if cached != nil {
+		sendEvent(c, "token", gin.H{"text": cached.Quote})
+		sendEvent(c, "done", cached)
+		return
+	}
+
+	result, err := client.StreamQuote(ctx, generator, tag, opts, func(token string) error {
+		sendEvent(c, "token", gin.H{"text": token})
+		return ctx.Err()
+	})
+	if err != nil {
+		h.recordSpent(consumer, err)
+		if _, errResp := h.generationError(tag, err); errResp != nil {
+			sendEvent(c, "failed", errResp)
+		}
+		return
+	}
+
+	h.recordUsage(consumer, result.Usage, result.CostUSD)
+
+	// The tokens are already out, so a duplicate cannot be regenerated
+	unlock := h.lockText(tag, result.Text)
+	defer unlock()
+	result, original, _ := h.deduplicate(ctx, tag, result, nil)
+	quote := h.newQuote(c, tag, opts, result, time.Since(startTime))
+	if original != nil {
+		if h.Dedup.Config.Mode == dedup.ModeReuse {
+			log.Printf("Serving stored quote %s instead of its duplicate: Tag=%s", original.ID, tag)
+			sendEvent(c, "done", newQuoteResponse(*original))
+			return
+		}
+		quote.DuplicateOf = duplicateRoot(original)
+	}
+
+	if errResp := h.screenQuote(ctx, quote); errResp != nil {
+		sendEvent(c, "failed", errResp)
+		return
+	}
+
+	// Save to database
+	if err := h.Store.Create(ctx, &quote); err != nil {
+		log.Printf("Error saving quote to database: %v", err)
+		sendEvent(c, "failed", ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to save quote",
+		})
+		return
+	}
+
+	log.Printf("Quote streamed successfully: ID=%s, Tag=%s, Latency=%dms", quote.ID, quote.Tag, quote.LatencyMs)
+
+	response := newQuoteResponse(quote)
+	h.cacheQuote(tag, opts, response)
+
+	sendEvent(c, "done", response)
+}
+
+// sendEvent writes a server-sent event and flushes it to the client
+func sendEvent(c *gin.Context, event string, data interface{}) {
+	c.SSEvent(event, data)
+	c.Writer.Flush()
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Adeel56/quotebox/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+// usageDateLayout is the format of the from and to query parameters
+const usageDateLayout = "2006-01-02"
+
+// UsageResponse is the response of GET /api/v1/usage
+type UsageResponse struct {
+	From    string           `json:"from"`
+	To      string           `json:"to"`
+	GroupBy []string         `json:"group_by"`
+	Rows    []store.StatsRow `json:"rows"`
+	Totals  store.StatsRow   `json:"totals"`
+}
+
+// GetUsage handles GET /api/v1/usage?from=YYYY-MM-DD&to=YYYY-MM-DD&group_by=day,model,tag
+//
+// The range is inclusive and defaults to the last 30 days; rows are grouped by
+// any combination of day, model and tag (all three by default). The report
+// exposes spending, so the route is restricted to admins.
+func (h *QuoteHandler) GetUsage(c *gin.Context) {
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	from := to.AddDate(0, 0, -29)
+
+	for param, target := range map[string]*time.Time{"from": &from, "to": &to} {
+		if value := c.Query(param); value != "" {
+			parsed, err := time.Parse(usageDateLayout, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, ErrorResponse{
+					Error:   "invalid_request",
+					Message: fmt.Sprintf("%s must be a date formatted as YYYY-MM-DD", param),
+				})
+				return
+			}
+			*target = parsed
+		}
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "from must not be after to",
+		})
+		return
+	}
+
+	groupBy := []string{store.GroupDay, store.GroupModel, store.GroupTag}
+	if value := c.Query("group_by"); value != "" {
+		groupBy = nil
+		seen := make(map[string]bool)
+		for _, group := range strings.Split(value, ",") {
+			group = strings.TrimSpace(group)
+			if !store.IsGroup(group) || seen[group] {
+				c.JSON(http.StatusBadRequest, ErrorResponse{
+					Error:   "invalid_request",
+					Message: "group_by must be a comma-separated list of distinct groups among day, model and tag",
+				})
+				return
+			}
+			seen[group] = true
+			groupBy = append(groupBy, group)
+		}
+	}
+
+	stats, err := h.Store.Stats(c.Request.Context(), store.StatsQuery{
+		From:    from,
+		To:      to.AddDate(0, 0, 1),
+		GroupBy: groupBy,
+	})
+	if err != nil {
+		log.Printf("Error aggregating usage: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to aggregate usage",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, UsageResponse{
+		From:    from.Format(usageDateLayout),
+		To:      to.Format(usageDateLayout),
+		GroupBy: groupBy,
+		Rows:    stats.Rows,
+		Totals:  stats.Totals,
+	})
+}
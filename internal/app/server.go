@@ -1,16 +1,28 @@
 package app
 
 import (
+	"context"
 	"embed"
+	"errors"
+	"io"
 	"io/fs"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Adeel56/quotebox/internal/app/handlers"
+	"github.com/Adeel56/quotebox/internal/budget"
+	"github.com/Adeel56/quotebox/internal/cache"
 	"github.com/Adeel56/quotebox/internal/client"
-	"github.com/Adeel56/quotebox/internal/db"
+	"github.com/Adeel56/quotebox/internal/dedup"
 	"github.com/Adeel56/quotebox/internal/metrics"
+	"github.com/Adeel56/quotebox/internal/moderation"
+	"github.com/Adeel56/quotebox/internal/pool"
+	"github.com/Adeel56/quotebox/internal/store"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
@@ -20,36 +32,113 @@ var frontendFS embed.FS
 
 // Server represents the application server
 type Server struct {
-	Router           *gin.Engine
-	OpenRouterClient *client.OpenRouterClient
-	QuoteHandler     *handlers.QuoteHandler
+	Router       *gin.Engine
+	Generator    client.QuoteGenerator
+	QuoteHandler *handlers.QuoteHandler
+	// Quotes stores the generated quotes and is closed on shutdown when it can be
+	Quotes store.QuoteStore
+
+	httpServer *http.Server
+	// baseCtx is the parent of every request context and is cancelled on shutdown
+	baseCtx    context.Context
+	cancelBase context.CancelFunc
+	// pool refills pre-generated quotes in the background, nil when disabled
+	pool *pool.Pool
 }
 
-// NewServer creates a new server instance
-func NewServer() *Server {
+// NewServer creates a new server instance storing quotes in quotes and
+// budget usage in budgets
+func NewServer(quotes store.QuoteStore, budgets budget.Store) *Server {
 	// Initialize metrics
 	metrics.Init()
 
-	// Initialize database
-	if err := db.InitDB(); err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+	// Initialize the configured quote generator and, when budgets fall back to
+	// other providers, their chain sharing the provider instances
+	budgetConfig := budget.ConfigFromEnv()
+	chains := [][]string{client.ConfiguredProviders()}
+	budgetFallback := budgetConfig.Enabled() && budgetConfig.Action == budget.ActionFallback
+	if budgetFallback {
+		chains = append(chains, budgetConfig.FallbackProviders)
+	}
+	generators, err := client.NewGenerators(chains...)
+	if err != nil {
+		log.Fatalf("Failed to initialize quote generators: %v", err)
 	}
+	generator := generators[0]
 
-	// Initialize OpenRouter client
-	openRouterClient := client.NewOpenRouterClient()
+	// Let identical concurrent requests share upstream calls when enabled
+	if coalesceConfig := client.CoalesceConfigFromEnv(); coalesceConfig.Enabled {
+		generator = client.WithCoalescing(generator, coalesceConfig)
+	}
+
+	models, err := client.NewModelAllowList()
+	if err != nil {
+		log.Fatalf("Failed to load model allow-list: %v", err)
+	}
 
 	// Create handlers
-	quoteHandler := handlers.NewQuoteHandler(openRouterClient)
+	quoteHandler := handlers.NewQuoteHandler(generator, quotes)
+	quoteHandler.Models = models
+	quoteHandler.Validation = client.NewPipeline(client.PipelineConfigFromEnv())
+
+	// Enforce spending limits when any budget is configured
+	if budgetConfig.Enabled() {
+		quoteHandler.Budget = budget.NewTracker(budgets, budgetConfig)
+		if budgetFallback {
+			quoteHandler.BudgetFallback = generators[1]
+		}
+	}
+
+	// Serve repeated requests from the cache when one is configured
+	cacheConfig := cache.ConfigFromEnv()
+	cacheBackend, err := cache.NewBackend(cacheConfig)
+	if err != nil {
+		log.Fatalf("Failed to initialize quote cache: %v", err)
+	}
+	if cacheBackend != nil {
+		quoteHandler.Cache = handlers.NewQuoteCache(cacheBackend, cacheConfig, quotes)
+	}
+
+	// Screen custom tags and generated quotes
+	contentFilter, err := moderation.NewFilter()
+	if err != nil {
+		log.Fatalf("Failed to load moderation rules: %v", err)
+	}
+	quoteHandler.Moderation = contentFilter
+
+	// Check new quotes against stored ones unless disabled
+	if dedupConfig := dedup.ConfigFromEnv(); dedupConfig.Enabled() {
+		quoteHandler.Dedup = dedup.NewDetector(dedupConfig)
+	}
+
+	// Keep pre-generated quotes for the preset tags when a pool size is configured
+	var quotePool *pool.Pool
+	if poolConfig := pool.ConfigFromEnv(); poolConfig.Enabled() {
+		quotePool = pool.New(generator, poolConfig)
+		quotePool.Budget = quoteHandler.Budget
+		quoteHandler.Pool = quotePool
+	}
+
+	quoteHandler.GenerationTimeout = getDurationEnv("QUOTE_GENERATION_TIMEOUT", 30*time.Second)
 
 	// Create server
+	baseCtx, cancelBase := context.WithCancel(context.Background())
 	server := &Server{
-		OpenRouterClient: openRouterClient,
-		QuoteHandler:     quoteHandler,
+		Generator:    generator,
+		QuoteHandler: quoteHandler,
+		Quotes:       quotes,
+		baseCtx:      baseCtx,
+		cancelBase:   cancelBase,
+		pool:         quotePool,
 	}
 
 	// Setup router
 	server.setupRouter()
 
+	if quotePool != nil {
+		quotePool.Start()
+	}
+
 	return server
 }
 
@@ -66,17 +155,34 @@ func (s *Server) setupRouter() {
 	// Metrics endpoint
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
+	// Admin endpoints need QUOTE_ADMIN_TOKEN as a bearer token
+	requireAdmin := handlers.RequireAdmin(os.Getenv("QUOTE_ADMIN_TOKEN"))
+
 	// API routes
 	apiV1 := router.Group("/api/v1")
 	{
 		apiV1.POST("/quote", s.QuoteHandler.CreateQuote)
+		apiV1.GET("/quote/stream", s.QuoteHandler.StreamQuote)
 		apiV1.GET("/quotes", s.QuoteHandler.GetQuotes)
+		apiV1.GET("/quotes/:id", s.QuoteHandler.GetQuote)
+		apiV1.PATCH("/quotes/:id", requireAdmin, s.QuoteHandler.UpdateQuote)
+		apiV1.DELETE("/quotes/:id", requireAdmin, s.QuoteHandler.DeleteQuote)
+		apiV1.POST("/quotes/:id/restore", requireAdmin, s.QuoteHandler.RestoreQuote)
 		apiV1.GET("/tags", s.QuoteHandler.GetTags)
+		apiV1.GET("/models", s.QuoteHandler.GetModels)
+		apiV1.GET("/usage", requireAdmin, s.QuoteHandler.GetUsage)
 	}
 
 	// Serve frontend static files
 	s.setupFrontend(router)
 
+	// Answer unknown API routes with a JSON error
+	router.NoRoute(func(c *gin.Context) {
+		if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+			handlers.NotFound(c)
+		}
+	})
+
 	s.Router = router
 }
 
@@ -129,20 +235,47 @@ func (s *Server) setupFrontend(router *gin.Engine) {
 
 // healthCheck handles GET /healthz
 func (s *Server) healthCheck(c *gin.Context) {
+	// Report provider health details such as circuit breaker state, including
+	// the providers only used once a budget is exhausted
+	var providers []client.ProviderStatus
+	listed := make(map[string]bool)
+	for _, generator := range []client.QuoteGenerator{s.Generator, s.QuoteHandler.BudgetFallback} {
+		reporter, ok := generator.(client.StatusReporter)
+		if !ok {
+			continue
+		}
+		for _, status := range reporter.ProviderStatuses() {
+			if !listed[status.Name] {
+				listed[status.Name] = true
+				providers = append(providers, status)
+			}
+		}
+	}
+
 	// Check database connection
-	if err := db.HealthCheck(); err != nil {
+	if err := s.pingStore(c.Request.Context()); err != nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"status": "unhealthy",
-			"error":  "database connection failed",
+			"status":    "unhealthy",
+			"error":     "database connection failed",
+			"providers": providers,
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"status": "ok",
+		"status":    "ok",
+		"providers": providers,
 	})
 }
 
+// pingStore checks the quote store's backend when it can be reached
+func (s *Server) pingStore(ctx context.Context) error {
+	if pinger, ok := s.Quotes.(store.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
 // metricsMiddleware records HTTP request metrics
 func (s *Server) metricsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -155,6 +288,10 @@ func (s *Server) metricsMiddleware() gin.HandlerFunc {
 			route = c.Request.URL.Path
 		}
 		status := http.StatusText(c.Writer.Status())
+		if status == "" {
+			// Non-standard codes such as 499 Client Closed Request
+			status = strconv.Itoa(c.Writer.Status())
+		}
 
 		metrics.RecordHTTPRequest(method, route, status)
 	}
@@ -167,12 +304,57 @@ func (s *Server) Run() error {
 		port = "8080"
 	}
 
+	s.httpServer = &http.Server{
+		Addr:    ":" + port,
+		Handler: s.Router,
+		BaseContext: func(net.Listener) context.Context {
+			return s.baseCtx
+		},
+	}
+
 	log.Printf("Starting server on port %s", port)
-	return s.Router.Run(":" + port)
+	if err := s.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
 
 // Shutdown gracefully shuts down the server
 func (s *Server) Shutdown() error {
 	log.Println("Shutting down server...")
-	return db.CloseDB()
+
+	// Abort in-flight generations so their upstream calls stop right away
+	s.cancelBase()
+
+	// Stop refilling the pool before the database goes away
+	if s.pool != nil {
+		s.pool.Stop()
+	}
+
+	if s.httpServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := s.httpServer.Shutdown(ctx); err != nil {
+			log.Printf("Error shutting down HTTP server: %v", err)
+		}
+	}
+
+	if closer, ok := s.Quotes.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// getDurationEnv returns a duration environment variable or the default
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed < 0 {
+		log.Printf("Warning: invalid %s %q, using %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
 }
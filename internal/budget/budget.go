@@ -0,0 +1,369 @@
+// Package budget enforces daily and monthly token and spending limits,
+// globally and per API consumer.
+package budget
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Adeel56/quotebox/internal/metrics"
+)
+
+// Budget periods
+const (
+	PeriodDay   = "day"
+	PeriodMonth = "month"
+)
+
+// Budget scopes, also used as the quote_budget_exceeded_total label
+const (
+	ScopeGlobal   = "global"
+	ScopeConsumer = "consumer"
+)
+
+// Actions taken once a budget is exhausted
+const (
+	ActionFallback = "fallback"
+	ActionReject   = "reject"
+)
+
+// globalKey is the storage key of the global budget
+const globalKey = "global"
+
+// maxConsumerLength bounds the consumer IDs stored as is; longer ones are
+// stored by their hash so the key fits the budget_usages.key column
+const maxConsumerLength = 100
+
+// consumerIDPattern matches the consumer IDs accepted in BUDGET_CONSUMER_KEYS
+var consumerIDPattern = regexp.MustCompile(`^[A-Za-z0-9._@-]{1,64}$`)
+
+// Limit caps the usage of one period. Zero values are unlimited.
+type Limit struct {
+	Tokens  int64
+	CostUSD float64
+}
+
+// Limits holds the daily and monthly limits of a scope
+type Limits struct {
+	Daily   Limit
+	Monthly Limit
+}
+
+// forPeriod returns the limit of the given period
+func (l Limits) forPeriod(period string) Limit {
+	if period == PeriodMonth {
+		return l.Monthly
+	}
+	return l.Daily
+}
+
+// enabled reports whether any limit is set
+func (l Limits) enabled() bool {
+	return l.Daily != (Limit{}) || l.Monthly != (Limit{})
+}
+
+// Config holds budget settings
+type Config struct {
+	Global   Limits
+	Consumer Limits
+	// Action is ActionFallback or ActionReject
+	Action string
+	// FallbackProviders are used instead of the configured chain when Action is ActionFallback
+	FallbackProviders []string
+	// ConsumerKeys maps the API keys identifying consumers to their IDs
+	ConsumerKeys map[string]string
+}
+
+// ConfigFromEnv reads BUDGET_{DAILY,MONTHLY}_{TOKENS,USD} for the global budget,
+// BUDGET_CONSUMER_{DAILY,MONTHLY}_{TOKENS,USD} for each consumer,
+// BUDGET_CONSUMER_KEYS, BUDGET_EXCEEDED_ACTION and BUDGET_FALLBACK_PROVIDERS
+func ConfigFromEnv() Config {
+	config := Config{
+		Global:            limitsFromEnv("BUDGET_"),
+		Consumer:          limitsFromEnv("BUDGET_CONSUMER_"),
+		Action:            ActionFallback,
+		FallbackProviders: []string{"corpus"},
+	}
+
+	switch action := strings.ToLower(os.Getenv("BUDGET_EXCEEDED_ACTION")); action {
+	case ActionFallback, ActionReject:
+		config.Action = action
+	case "":
+	default:
+		log.Printf("Warning: unknown BUDGET_EXCEEDED_ACTION %q, using %q", action, config.Action)
+	}
+
+	if value := os.Getenv("BUDGET_FALLBACK_PROVIDERS"); value != "" {
+		config.FallbackProviders = nil
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+				config.FallbackProviders = append(config.FallbackProviders, name)
+			}
+		}
+	}
+
+	config.ConsumerKeys = consumerKeysFromEnv()
+
+	return config
+}
+
+// consumerKeysFromEnv reads BUDGET_CONSUMER_KEYS, a comma-separated list of
+// id:key pairs. Invalid entries are skipped.
+func consumerKeysFromEnv() map[string]string {
+	value := os.Getenv("BUDGET_CONSUMER_KEYS")
+	if value == "" {
+		return nil
+	}
+
+	keys := make(map[string]string)
+	for i, entry := range strings.Split(value, ",") {
+		id, key, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || !consumerIDPattern.MatchString(id) || key == "" {
+			// The entry holds a secret, so only its position is logged
+			log.Printf("Warning: invalid BUDGET_CONSUMER_KEYS entry %d, ignoring it", i+1)
+			continue
+		}
+		keys[key] = id
+	}
+	return keys
+}
+
+// limitsFromEnv reads the limits with the given variable prefix
+func limitsFromEnv(prefix string) Limits {
+	return Limits{
+		Daily: Limit{
+			Tokens:  int64Env(prefix + "DAILY_TOKENS"),
+			CostUSD: floatEnv(prefix + "DAILY_USD"),
+		},
+		Monthly: Limit{
+			Tokens:  int64Env(prefix + "MONTHLY_TOKENS"),
+			CostUSD: floatEnv(prefix + "MONTHLY_USD"),
+		},
+	}
+}
+
+func int64Env(key string) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return 0
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || parsed < 0 {
+		log.Printf("Warning: invalid %s %q, ignoring it", key, value)
+		return 0
+	}
+	return parsed
+}
+
+func floatEnv(key string) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return 0
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil || parsed < 0 {
+		log.Printf("Warning: invalid %s %q, ignoring it", key, value)
+		return 0
+	}
+	return parsed
+}
+
+// Enabled reports whether any budget is configured
+func (c Config) Enabled() bool {
+	return c.Global.enabled() || c.Consumer.enabled()
+}
+
+// Usage is the tokens and money spent during a period
+type Usage struct {
+	Tokens  int64
+	CostUSD float64
+}
+
+// exceeds reports which unit of the limit the usage has reached, if any
+func (u Usage) exceeds(limit Limit) (string, bool) {
+	if limit.Tokens > 0 && u.Tokens >= limit.Tokens {
+		return "tokens", true
+	}
+	if limit.CostUSD > 0 && u.CostUSD >= limit.CostUSD {
+		return "usd", true
+	}
+	return "", false
+}
+
+// Store persists budget usage per key and period
+type Store interface {
+	// Usage returns the usage of key during the period starting at start
+	Usage(ctx context.Context, key, period string, start time.Time) (Usage, error)
+	// Add adds usage to key for the period starting at start and returns the new total
+	Add(ctx context.Context, key, period string, start time.Time, usage Usage) (Usage, error)
+}
+
+// ExceededError is returned when a budget is exhausted
+type ExceededError struct {
+	Scope   string
+	Period  string
+	Unit    string
+	ResetAt time.Time
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("%s %s %s budget exceeded until %s", e.Scope, e.Period, e.Unit, e.ResetAt.Format(time.RFC3339))
+}
+
+// Tracker checks and records usage against the configured budgets.
+// Usage is checked before a generation and recorded after it, so concurrent
+// requests may overshoot a limit by the cost of the generations in flight.
+type Tracker struct {
+	store  Store
+	config Config
+	now    func() time.Time
+}
+
+// NewTracker creates a budget tracker
+func NewTracker(store Store, config Config) *Tracker {
+	t := &Tracker{
+		store:  store,
+		config: config,
+		now:    time.Now,
+	}
+	for _, period := range []string{PeriodDay, PeriodMonth} {
+		limit := config.Global.forPeriod(period)
+		metrics.SetBudgetLimit(period, float64(limit.Tokens), limit.CostUSD)
+	}
+	t.loadUsageMetrics(context.Background())
+	return t
+}
+
+// loadUsageMetrics sets the global budget usage gauges from the store, so they
+// survive restarts
+func (t *Tracker) loadUsageMetrics(ctx context.Context) {
+	now := t.now()
+	for _, period := range []string{PeriodDay, PeriodMonth} {
+		start, _ := periodBounds(period, now)
+		usage, err := t.store.Usage(ctx, globalKey, period, start)
+		if err != nil {
+			log.Printf("Error loading global %s budget usage: %v", period, err)
+			continue
+		}
+		metrics.SetBudgetUsage(period, float64(usage.Tokens), usage.CostUSD)
+	}
+}
+
+// SetClock replaces the tracker's clock, for tests
+func (t *Tracker) SetClock(now func() time.Time) {
+	t.now = now
+}
+
+// Config returns the tracker's configuration
+func (t *Tracker) Config() Config {
+	return t.config
+}
+
+// Consumer returns the ID of the consumer identified by the API key, or false
+// when the key is unknown
+func (t *Tracker) Consumer(apiKey string) (string, bool) {
+	if apiKey == "" {
+		return "", false
+	}
+	for key, id := range t.config.ConsumerKeys {
+		if subtle.ConstantTimeCompare([]byte(apiKey), []byte(key)) == 1 {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// Check returns an *ExceededError if the global or the consumer's budget is exhausted
+func (t *Tracker) Check(ctx context.Context, consumer string) error {
+	now := t.now()
+	for _, period := range []string{PeriodDay, PeriodMonth} {
+		start, reset := periodBounds(period, now)
+
+		scopes := []struct {
+			scope  string
+			key    string
+			limits Limits
+		}{
+			{ScopeGlobal, globalKey, t.config.Global},
+			{ScopeConsumer, consumerKey(consumer), t.config.Consumer},
+		}
+		for _, s := range scopes {
+			limit := s.limits.forPeriod(period)
+			if limit == (Limit{}) || s.key == "" {
+				continue
+			}
+
+			usage, err := t.store.Usage(ctx, s.key, period, start)
+			if err != nil {
+				return fmt.Errorf("failed to load %s budget usage: %w", s.scope, err)
+			}
+			// Keeps the gauges current when a new period starts
+			if s.scope == ScopeGlobal {
+				metrics.SetBudgetUsage(period, float64(usage.Tokens), usage.CostUSD)
+			}
+			if unit, exceeded := usage.exceeds(limit); exceeded {
+				return &ExceededError{Scope: s.scope, Period: period, Unit: unit, ResetAt: reset}
+			}
+		}
+	}
+	return nil
+}
+
+// Record adds the usage of a generation to the global and the consumer's budgets,
+// including the tokens of failed and rejected attempts
+func (t *Tracker) Record(ctx context.Context, consumer string, tokens int, costUSD float64) error {
+	if tokens == 0 && costUSD == 0 {
+		return nil
+	}
+
+	now := t.now()
+	usage := Usage{Tokens: int64(tokens), CostUSD: costUSD}
+	for _, period := range []string{PeriodDay, PeriodMonth} {
+		start, _ := periodBounds(period, now)
+
+		total, err := t.store.Add(ctx, globalKey, period, start, usage)
+		if err != nil {
+			return fmt.Errorf("failed to record global budget usage: %w", err)
+		}
+		metrics.SetBudgetUsage(period, float64(total.Tokens), total.CostUSD)
+
+		if key := consumerKey(consumer); key != "" && t.config.Consumer.enabled() {
+			if _, err := t.store.Add(ctx, key, period, start, usage); err != nil {
+				return fmt.Errorf("failed to record consumer budget usage: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// consumerKey returns the storage key of a consumer's budget
+func consumerKey(consumer string) string {
+	if consumer == "" {
+		return ""
+	}
+	if len(consumer) > maxConsumerLength {
+		sum := sha256.Sum256([]byte(consumer))
+		return "consumer:sha256:" + hex.EncodeToString(sum[:])
+	}
+	return "consumer:" + consumer
+}
+
+// periodBounds returns the UTC start of the period containing now and the start of the next one
+func periodBounds(period string, now time.Time) (time.Time, time.Time) {
+	now = now.UTC()
+	if period == PeriodMonth {
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	}
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 0, 1)
+}
@@ -0,0 +1,92 @@
+package budget
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Adeel56/quotebox/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormStore keeps budget usage in the budget_usages table
+type GormStore struct {
+	DB *gorm.DB
+}
+
+// NewGormStore creates a store backed by the given database
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{DB: db}
+}
+
+// Usage returns the usage of key during the period starting at start
+func (s *GormStore) Usage(ctx context.Context, key, period string, start time.Time) (Usage, error) {
+	var rows []models.BudgetUsage
+	err := s.DB.WithContext(ctx).
+		Where("key = ? AND period = ? AND period_start = ?", key, period, start).
+		Limit(1).
+		Find(&rows).Error
+	if err != nil || len(rows) == 0 {
+		return Usage{}, err
+	}
+	return Usage{Tokens: rows[0].Tokens, CostUSD: rows[0].CostUSD}, nil
+}
+
+// Add atomically adds usage to key for the period starting at start
+func (s *GormStore) Add(ctx context.Context, key, period string, start time.Time, usage Usage) (Usage, error) {
+	row := models.BudgetUsage{
+		Key:         key,
+		Period:      period,
+		PeriodStart: start,
+		Tokens:      usage.Tokens,
+		CostUSD:     usage.CostUSD,
+	}
+	err := s.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "key"}, {Name: "period"}, {Name: "period_start"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"tokens":     gorm.Expr("budget_usages.tokens + ?", usage.Tokens),
+			"cost_usd":   gorm.Expr("budget_usages.cost_usd + ?", usage.CostUSD),
+			"updated_at": time.Now(),
+		}),
+	}).Create(&row).Error
+	if err != nil {
+		return Usage{}, err
+	}
+	return s.Usage(ctx, key, period, start)
+}
+
+// MemoryStore keeps budget usage in memory; usage is lost on restart
+type MemoryStore struct {
+	mu    sync.Mutex
+	usage map[string]Usage
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{usage: make(map[string]Usage)}
+}
+
+// Usage returns the usage of key during the period starting at start
+func (s *MemoryStore) Usage(ctx context.Context, key, period string, start time.Time) (Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.usage[memoryKey(key, period, start)], nil
+}
+
+// Add adds usage to key for the period starting at start
+func (s *MemoryStore) Add(ctx context.Context, key, period string, start time.Time, usage Usage) (Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := memoryKey(key, period, start)
+	total := s.usage[k]
+	total.Tokens += usage.Tokens
+	total.CostUSD += usage.CostUSD
+	s.usage[k] = total
+	return total, nil
+}
+
+func memoryKey(key, period string, start time.Time) string {
+	return key + "|" + period + "|" + start.Format(time.RFC3339)
+}
@@ -0,0 +1,98 @@
+// Package cache provides the key-value backends used to serve repeated quote
+// requests without calling a provider.
+package cache
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Backends accepted by QUOTE_CACHE
+const (
+	BackendOff    = "off"
+	BackendMemory = "memory"
+)
+
+// Backend stores values with a time to live. Implementations must be safe for
+// concurrent use; the interface maps directly onto Redis GET and SET EX.
+type Backend interface {
+	// Get returns the value stored under key, or false if it is missing or expired
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key for ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// Config holds cache settings
+type Config struct {
+	Backend string
+	// Size is the maximum number of entries kept by the memory backend
+	Size int
+	// TTL is how long a generated quote is served again for the same request
+	TTL time.Duration
+	// ReuseStored serves stored quotes for the same tag and options that
+	// have not been shown within ReuseAfter instead of generating new ones
+	ReuseStored bool
+	ReuseAfter  time.Duration
+}
+
+// ConfigFromEnv reads QUOTE_CACHE, QUOTE_CACHE_SIZE, QUOTE_CACHE_TTL,
+// QUOTE_CACHE_REUSE_STORED and QUOTE_CACHE_REUSE_AFTER
+func ConfigFromEnv() Config {
+	config := Config{
+		Backend:    BackendOff,
+		Size:       1000,
+		TTL:        10 * time.Minute,
+		ReuseAfter: 24 * time.Hour,
+	}
+
+	if value := os.Getenv("QUOTE_CACHE"); value != "" {
+		config.Backend = strings.ToLower(value)
+	}
+	if value := os.Getenv("QUOTE_CACHE_SIZE"); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+			config.Size = parsed
+		} else {
+			log.Printf("Warning: invalid QUOTE_CACHE_SIZE %q, using %d", value, config.Size)
+		}
+	}
+	if value := os.Getenv("QUOTE_CACHE_TTL"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed >= 0 {
+			config.TTL = parsed
+		} else {
+			log.Printf("Warning: invalid QUOTE_CACHE_TTL %q, using %s", value, config.TTL)
+		}
+	}
+	if value := os.Getenv("QUOTE_CACHE_REUSE_STORED"); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			config.ReuseStored = parsed
+		} else {
+			log.Printf("Warning: invalid QUOTE_CACHE_REUSE_STORED %q, ignoring it", value)
+		}
+	}
+	if value := os.Getenv("QUOTE_CACHE_REUSE_AFTER"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			config.ReuseAfter = parsed
+		} else {
+			log.Printf("Warning: invalid QUOTE_CACHE_REUSE_AFTER %q, using %s", value, config.ReuseAfter)
+		}
+	}
+
+	return config
+}
+
+// NewBackend creates the configured backend, or nil when caching is off
+func NewBackend(config Config) (Backend, error) {
+	switch config.Backend {
+	case BackendOff, "":
+		return nil, nil
+	case BackendMemory:
+		return NewLRU(config.Size), nil
+	default:
+		return nil, fmt.Errorf("unknown QUOTE_CACHE backend %q", config.Backend)
+	}
+}
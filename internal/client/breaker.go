@@ -0,0 +1,253 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Adeel56/quotebox/internal/metrics"
+)
+
+// ErrCircuitOpen is returned when a call is rejected by an open circuit breaker
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState is the state of a circuit breaker
+type BreakerState int
+
+// Circuit breaker states, also exported as the circuit_breaker_state gauge value
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// BreakerConfig holds circuit breaker settings
+type BreakerConfig struct {
+	FailureThreshold int
+	CoolDown         time.Duration
+}
+
+// BreakerConfigFromEnv reads CIRCUIT_BREAKER_FAILURE_THRESHOLD and CIRCUIT_BREAKER_COOLDOWN.
+// A threshold of 0 disables the breaker.
+func BreakerConfigFromEnv() BreakerConfig {
+	config := BreakerConfig{
+		FailureThreshold: 5,
+		CoolDown:         30 * time.Second,
+	}
+
+	if value := os.Getenv("CIRCUIT_BREAKER_FAILURE_THRESHOLD"); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed >= 0 {
+			config.FailureThreshold = parsed
+		} else {
+			log.Printf("Warning: invalid CIRCUIT_BREAKER_FAILURE_THRESHOLD %q, using %d", value, config.FailureThreshold)
+		}
+	}
+
+	if value := os.Getenv("CIRCUIT_BREAKER_COOLDOWN"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			config.CoolDown = parsed
+		} else {
+			log.Printf("Warning: invalid CIRCUIT_BREAKER_COOLDOWN %q, using %s", value, config.CoolDown)
+		}
+	}
+
+	return config
+}
+
+// CircuitBreaker stops calls to a failing dependency for a cool-down period.
+// After FailureThreshold consecutive failures the circuit opens; once the
+// cool-down has elapsed a single trial call is let through (half-open) and
+// its outcome either closes the circuit or opens it again.
+type CircuitBreaker struct {
+	name   string
+	config BreakerConfig
+
+	mu            sync.Mutex
+	state         BreakerState
+	failures      int
+	openedAt      time.Time
+	trialInFlight bool
+}
+
+// NewCircuitBreaker creates a closed circuit breaker
+func NewCircuitBreaker(name string, config BreakerConfig) *CircuitBreaker {
+	b := &CircuitBreaker{
+		name:   name,
+		config: config,
+	}
+	metrics.SetCircuitBreakerState(name, int(BreakerClosed))
+	return b
+}
+
+// Allow reports whether a call may proceed, moving an expired open circuit to half-open
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.config.CoolDown {
+			return false
+		}
+		b.setState(BreakerHalfOpen)
+		b.trialInFlight = true
+		return true
+	case BreakerHalfOpen:
+		if b.trialInFlight {
+			return false
+		}
+		b.trialInFlight = true
+		return true
+	default:
+		return true
+	}
+}
+
+// Ready reports whether Allow would let a call through, without changing state
+func (b *CircuitBreaker) Ready() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		return time.Since(b.openedAt) >= b.config.CoolDown
+	case BreakerHalfOpen:
+		return !b.trialInFlight
+	default:
+		return true
+	}
+}
+
+// Success records a successful call and closes the circuit
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.trialInFlight = false
+	if b.state != BreakerClosed {
+		log.Printf("Circuit breaker for %s closed", b.name)
+		b.setState(BreakerClosed)
+	}
+}
+
+// Failure records a failed call, opening the circuit when the threshold is reached
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.trialInFlight = false
+	if b.state == BreakerHalfOpen || b.failures >= b.config.FailureThreshold {
+		if b.state != BreakerOpen {
+			log.Printf("Circuit breaker for %s opened after %d failures", b.name, b.failures)
+		}
+		b.openedAt = time.Now()
+		b.setState(BreakerOpen)
+	}
+}
+
+// Release gives back a half-open trial whose outcome says nothing about the dependency
+func (b *CircuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trialInFlight = false
+}
+
+// State returns the current breaker state
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// setState updates the state and its gauge; callers must hold mu
+func (b *CircuitBreaker) setState(state BreakerState) {
+	b.state = state
+	metrics.SetCircuitBreakerState(b.name, int(state))
+}
+
+// BreakerGenerator guards a provider with a circuit breaker
+type BreakerGenerator struct {
+	QuoteGenerator
+	Breaker *CircuitBreaker
+}
+
+// WithCircuitBreaker wraps a provider in a circuit breaker
+func WithCircuitBreaker(generator QuoteGenerator, config BreakerConfig) *BreakerGenerator {
+	return &BreakerGenerator{
+		QuoteGenerator: generator,
+		Breaker:        NewCircuitBreaker(generator.Name(), config),
+	}
+}
+
+// GenerateQuote calls the wrapped provider unless the circuit is open
+func (g *BreakerGenerator) GenerateQuote(ctx context.Context, tag string, opts GenerateOptions) (*GenerationResult, error) {
+	return g.guard(func() (*GenerationResult, error) {
+		return g.QuoteGenerator.GenerateQuote(ctx, tag, opts)
+	})
+}
+
+// StreamQuote streams from the wrapped provider unless the circuit is open
+func (g *BreakerGenerator) StreamQuote(ctx context.Context, tag string, opts GenerateOptions, onToken TokenFunc) (*GenerationResult, error) {
+	return g.guard(func() (*GenerationResult, error) {
+		return StreamQuote(ctx, g.QuoteGenerator, tag, opts, onToken)
+	})
+}
+
+// guard runs call through the circuit breaker
+func (g *BreakerGenerator) guard(call func() (*GenerationResult, error)) (*GenerationResult, error) {
+	if !g.Breaker.Allow() {
+		return nil, ErrCircuitOpen
+	}
+
+	result, err := call()
+	if err != nil {
+		// A client that disconnected is not an upstream failure
+		if errors.Is(err, context.Canceled) {
+			g.Breaker.Release()
+			return nil, err
+		}
+		// A quote rejected by the pipeline still came from a healthy upstream
+		var rejection *RejectionError
+		if errors.As(err, &rejection) {
+			g.Breaker.Success()
+			return nil, err
+		}
+		g.Breaker.Failure()
+		return nil, err
+	}
+
+	g.Breaker.Success()
+	return result, nil
+}
+
+// Available reports whether the circuit currently lets calls through
+func (g *BreakerGenerator) Available() bool {
+	return g.Breaker.Ready()
+}
+
+// ProviderStatuses reports the breaker state of the wrapped provider
+func (g *BreakerGenerator) ProviderStatuses() []ProviderStatus {
+	return []ProviderStatus{
+		{
+			Name:    g.Name(),
+			Circuit: g.Breaker.State().String(),
+		},
+	}
+}
@@ -0,0 +1,107 @@
+package client
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed catalog/models.yaml
+var modelCatalog []byte
+
+// ModelInfo describes a model that can be requested per quote
+type ModelInfo struct {
+	ID            string `yaml:"id" json:"id"`
+	Name          string `yaml:"name" json:"name,omitempty"`
+	Description   string `yaml:"description" json:"description,omitempty"`
+	ContextLength int    `yaml:"context_length" json:"context_length,omitempty"`
+	Default       bool   `yaml:"-" json:"default"`
+}
+
+// ModelAllowList holds the models callers may request
+type ModelAllowList struct {
+	models       []ModelInfo
+	byID         map[string]int
+	defaultModel string
+}
+
+// NewModelAllowList builds the allow-list from OPENROUTER_ALLOWED_MODELS, a
+// comma-separated list of model ids. The default model is always allowed.
+func NewModelAllowList() (*ModelAllowList, error) {
+	var ids []string
+	for _, id := range strings.Split(os.Getenv("OPENROUTER_ALLOWED_MODELS"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+
+	catalog, err := LoadModelCatalog()
+	if err != nil {
+		return nil, err
+	}
+	return NewModelAllowListFromIDs(defaultOpenRouterModel(), ids, catalog), nil
+}
+
+// LoadModelCatalog returns the metadata of the known models
+func LoadModelCatalog() ([]ModelInfo, error) {
+	var catalog []ModelInfo
+	if err := yaml.Unmarshal(modelCatalog, &catalog); err != nil {
+		return nil, fmt.Errorf("invalid model catalog: %w", err)
+	}
+	return catalog, nil
+}
+
+// NewModelAllowListFromIDs creates an allow-list of the default model and ids,
+// describing each with its catalog entry when there is one
+func NewModelAllowListFromIDs(defaultModel string, ids []string, catalog []ModelInfo) *ModelAllowList {
+	known := make(map[string]ModelInfo, len(catalog))
+	for _, info := range catalog {
+		known[info.ID] = info
+	}
+
+	l := &ModelAllowList{
+		byID:         make(map[string]int),
+		defaultModel: defaultModel,
+	}
+	for _, id := range append([]string{defaultModel}, ids...) {
+		if _, ok := l.byID[id]; ok {
+			continue
+		}
+		info, ok := known[id]
+		if !ok {
+			info = ModelInfo{ID: id}
+		}
+		info.Default = id == defaultModel
+		l.byID[id] = len(l.models)
+		l.models = append(l.models, info)
+	}
+	return l
+}
+
+// Allowed reports whether the model may be requested
+func (l *ModelAllowList) Allowed(id string) bool {
+	_, ok := l.byID[id]
+	return ok
+}
+
+// Models returns the allowed models, default first
+func (l *ModelAllowList) Models() []ModelInfo {
+	return l.models
+}
+
+// IDs returns the ids of the allowed models
+func (l *ModelAllowList) IDs() []string {
+	ids := make([]string, len(l.models))
+	for i, info := range l.models {
+		ids[i] = info.ID
+	}
+	return ids
+}
+
+// Default returns the model used when a request does not name one
+func (l *ModelAllowList) Default() string {
+	return l.defaultModel
+}
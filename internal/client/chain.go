@@ -0,0 +1,138 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/Adeel56/quotebox/internal/metrics"
+)
+
+// ErrProviderUnavailable is returned for providers skipped without being called
+var ErrProviderUnavailable = errors.New("provider unavailable")
+
+// AvailabilityChecker is implemented by providers that know they cannot serve
+// a request without calling upstream, e.g. while their circuit is open
+type AvailabilityChecker interface {
+	Available() bool
+}
+
+// ProviderStatus describes the health of a single provider
+type ProviderStatus struct {
+	Name    string `json:"name"`
+	Circuit string `json:"circuit,omitempty"`
+}
+
+// StatusReporter is implemented by generators that can describe their providers' health
+type StatusReporter interface {
+	ProviderStatuses() []ProviderStatus
+}
+
+// FallbackGenerator tries an ordered list of providers until one succeeds
+type FallbackGenerator struct {
+	Providers []QuoteGenerator
+}
+
+// NewFallbackGenerator creates a fallback chain over the given providers
+func NewFallbackGenerator(providers ...QuoteGenerator) *FallbackGenerator {
+	// Providers count as down (0) until they have served a quote
+	for _, provider := range providers {
+		metrics.InitProviderStatus(provider.Name())
+	}
+
+	return &FallbackGenerator{
+		Providers: providers,
+	}
+}
+
+// Name returns the provider identifier
+func (f *FallbackGenerator) Name() string {
+	return "fallback"
+}
+
+// GenerateQuote asks each provider in order and returns the first quote produced
+func (f *FallbackGenerator) GenerateQuote(ctx context.Context, tag string, opts GenerateOptions) (*GenerationResult, error) {
+	return f.try(ctx, func(provider QuoteGenerator) (*GenerationResult, error) {
+		return provider.GenerateQuote(ctx, tag, opts)
+	}, nil)
+}
+
+// StreamQuote streams from each provider in order until one succeeds.
+// Providers that cannot stream deliver their quote as a single token.
+func (f *FallbackGenerator) StreamQuote(ctx context.Context, tag string, opts GenerateOptions, onToken TokenFunc) (*GenerationResult, error) {
+	emitted := false
+	track := func(token string) error {
+		emitted = true
+		return onToken(token)
+	}
+
+	return f.try(ctx, func(provider QuoteGenerator) (*GenerationResult, error) {
+		return StreamQuote(ctx, provider, tag, opts, track)
+	}, func() bool {
+		return emitted
+	})
+}
+
+// try runs call against each available provider in order. committed, when set,
+// reports whether output already reached the caller, in which case a failure
+// cannot be masked by falling back to the next provider. Tokens spent by
+// failed providers are added to the result or the returned error.
+func (f *FallbackGenerator) try(ctx context.Context, call func(QuoteGenerator) (*GenerationResult, error), committed func() bool) (*GenerationResult, error) {
+	var errs []error
+	var spent Usage
+	var spentCost float64
+	for _, provider := range f.Providers {
+		name := provider.Name()
+
+		if checker, ok := provider.(AvailabilityChecker); ok && !checker.Available() {
+			log.Printf("Skipping quote provider %s: unavailable", name)
+			metrics.SetProviderStatus(name, false)
+			errs = append(errs, fmt.Errorf("%s: %w", name, ErrProviderUnavailable))
+			continue
+		}
+
+		result, err := call(provider)
+		if err != nil {
+			usage, cost := SpentUsage(err)
+			spent.add(usage)
+			spentCost += cost
+
+			// The caller is gone or out of time, the next provider would not help
+			if ctx.Err() != nil {
+				return nil, withUsage(ctx.Err(), spent, spentCost)
+			}
+
+			log.Printf("Quote provider %s failed: %v", name, err)
+			metrics.SetProviderStatus(name, false)
+			if committed != nil && committed() {
+				return nil, withUsage(fmt.Errorf("%s: %w", name, err), spent, spentCost)
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+
+		metrics.SetProviderStatus(name, true)
+		result.addUsage(spent, spentCost)
+		result.Source = name
+		for _, alternative := range result.Alternatives {
+			alternative.Source = name
+		}
+		return result, nil
+	}
+
+	return nil, withUsage(fmt.Errorf("all quote providers failed: %w", errors.Join(errs...)), spent, spentCost)
+}
+
+// ProviderStatuses reports the health of every provider in the chain
+func (f *FallbackGenerator) ProviderStatuses() []ProviderStatus {
+	var statuses []ProviderStatus
+	for _, provider := range f.Providers {
+		if reporter, ok := provider.(StatusReporter); ok {
+			statuses = append(statuses, reporter.ProviderStatuses()...)
+			continue
+		}
+		statuses = append(statuses, ProviderStatus{Name: provider.Name()})
+	}
+	return statuses
+}
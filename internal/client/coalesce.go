@@ -0,0 +1,187 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/Adeel56/quotebox/internal/metrics"
+)
+
+// CoalesceConfig controls request coalescing
+type CoalesceConfig struct {
+	Enabled bool
+	// Candidates is the number of quotes requested per upstream call and
+	// handed out to the requests waiting on it; 1 gives every waiter the same quote
+	Candidates int
+}
+
+// CoalesceConfigFromEnv reads QUOTE_COALESCE and QUOTE_COALESCE_CANDIDATES
+func CoalesceConfigFromEnv() CoalesceConfig {
+	config := CoalesceConfig{Candidates: 1}
+
+	if value := os.Getenv("QUOTE_COALESCE"); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			config.Enabled = parsed
+		} else {
+			log.Printf("Warning: invalid QUOTE_COALESCE %q, ignoring it", value)
+		}
+	}
+	if value := os.Getenv("QUOTE_COALESCE_CANDIDATES"); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed >= 1 {
+			config.Candidates = parsed
+		} else {
+			log.Printf("Warning: invalid QUOTE_COALESCE_CANDIDATES %q, using %d", value, config.Candidates)
+		}
+	}
+
+	return config
+}
+
+// CoalescingGenerator lets concurrent requests for the same tag and options
+// share one upstream call. With several candidates per call each waiter gets
+// a distinct quote while they last; later waiters share the first one.
+type CoalescingGenerator struct {
+	QuoteGenerator
+	Candidates int
+
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// flight is an upstream call shared by its waiters
+type flight struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+	results []*GenerationResult
+	taken   int
+	err     error
+}
+
+// WithCoalescing wraps a generator so identical concurrent requests share calls
+func WithCoalescing(generator QuoteGenerator, config CoalesceConfig) *CoalescingGenerator {
+	candidates := config.Candidates
+	if candidates < 1 {
+		candidates = 1
+	}
+	return &CoalescingGenerator{
+		QuoteGenerator: generator,
+		Candidates:     candidates,
+		flights:        make(map[string]*flight),
+	}
+}
+
+// coalesceKey identifies the requests that can share a generation
+func coalesceKey(tag string, opts GenerateOptions) string {
+	return fmt.Sprintf("%q|%+v", tag, opts)
+}
+
+// GenerateQuote joins the call in flight for the same tag and options, or starts one.
+// The call is cancelled only once every waiter has gone away.
+func (g *CoalescingGenerator) GenerateQuote(ctx context.Context, tag string, opts GenerateOptions) (*GenerationResult, error) {
+	key := coalesceKey(tag, opts)
+
+	g.mu.Lock()
+	f, ok := g.flights[key]
+	if ok {
+		metrics.RecordQuoteCoalesced()
+	} else {
+		var flightCtx context.Context
+		f = &flight{done: make(chan struct{})}
+		flightCtx, f.cancel = context.WithCancel(context.Background())
+		g.flights[key] = f
+		go g.run(flightCtx, key, f, tag, opts)
+	}
+	f.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return g.take(f)
+	case <-ctx.Done():
+		g.mu.Lock()
+		f.waiters--
+		if f.waiters == 0 {
+			f.cancel()
+			// Requests arriving from now on start a new call instead of joining a cancelled one
+			g.forget(key, f)
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// forget removes the flight from the flights in progress unless a newer one
+// replaced it. g.mu must be held.
+func (g *CoalescingGenerator) forget(key string, f *flight) {
+	if g.flights[key] == f {
+		delete(g.flights, key)
+	}
+}
+
+// run makes the shared upstream call
+func (g *CoalescingGenerator) run(ctx context.Context, key string, f *flight, tag string, opts GenerateOptions) {
+	defer f.cancel()
+
+	if g.Candidates > 1 {
+		opts.Candidates = g.Candidates
+	}
+	result, err := g.QuoteGenerator.GenerateQuote(ctx, tag, opts)
+	if err == nil {
+		f.results = append([]*GenerationResult{result}, result.Alternatives...)
+		result.Alternatives = nil
+	}
+
+	g.mu.Lock()
+	f.err = err
+	// Requests arriving from now on start a new call
+	g.forget(key, f)
+	g.mu.Unlock()
+	close(f.done)
+}
+
+// take hands the next unclaimed candidate to a waiter. Only the first carries
+// the call's usage, also when it failed. Waiters left without a candidate get
+// a copy of the first one, marked as shared.
+func (g *CoalescingGenerator) take(f *flight) (*GenerationResult, error) {
+	g.mu.Lock()
+	index := f.taken
+	f.taken++
+	g.mu.Unlock()
+
+	if f.err != nil {
+		if index > 0 {
+			// Hide the usage of the failed call, it is accounted by the first waiter
+			return nil, &UsageError{Err: f.err}
+		}
+		return nil, f.err
+	}
+
+	if index < len(f.results) {
+		result := *f.results[index]
+		return &result, nil
+	}
+
+	result := *f.results[0]
+	result.Usage = Usage{}
+	result.CostUSD = 0
+	result.Shared = true
+	return &result, nil
+}
+
+// StreamQuote streams from the wrapped generator; streams are never shared
+func (g *CoalescingGenerator) StreamQuote(ctx context.Context, tag string, opts GenerateOptions, onToken TokenFunc) (*GenerationResult, error) {
+	return StreamQuote(ctx, g.QuoteGenerator, tag, opts, onToken)
+}
+
+// ProviderStatuses reports the health of the wrapped generator's providers
+func (g *CoalescingGenerator) ProviderStatuses() []ProviderStatus {
+	if reporter, ok := g.QuoteGenerator.(StatusReporter); ok {
+		return reporter.ProviderStatuses()
+	}
+	return []ProviderStatus{{Name: g.Name()}}
+}
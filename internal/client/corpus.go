@@ -0,0 +1,299 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed corpus/quotes.json
+var corpusFS embed.FS
+
+// Corpus file formats accepted by ParseCorpus
+const (
+	CorpusFormatJSON = "json"
+	CorpusFormatCSV  = "csv"
+	CorpusFormatYAML = "yaml"
+)
+
+// CorpusEntry is a single attributed quote in the curated corpus
+type CorpusEntry struct {
+	Text   string   `json:"text" yaml:"text"`
+	Author string   `json:"author" yaml:"author"`
+	Tags   []string `json:"tags" yaml:"tags"`
+}
+
+// CorpusClient serves quotes from a curated corpus without any network calls
+type CorpusClient struct {
+	entries []CorpusEntry
+	byTag   map[string][]int
+	tags    []string
+}
+
+// NewCorpusClient loads the corpus from QUOTE_CORPUS_PATH or the bundled dataset
+func NewCorpusClient() (*CorpusClient, error) {
+	var (
+		entries []CorpusEntry
+		err     error
+	)
+
+	if path := os.Getenv("QUOTE_CORPUS_PATH"); path != "" {
+		entries, err = LoadCorpusFile(path)
+	} else {
+		var data []byte
+		data, err = corpusFS.ReadFile("corpus/quotes.json")
+		if err == nil {
+			entries, err = ParseCorpus(data, CorpusFormatJSON)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load quote corpus: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("quote corpus is empty")
+	}
+
+	log.Printf("Loaded quote corpus with %d entries", len(entries))
+	return NewCorpusClientFromEntries(entries), nil
+}
+
+// NewCorpusClientFromEntries creates a corpus client from in-memory entries
+func NewCorpusClientFromEntries(entries []CorpusEntry) *CorpusClient {
+	c := &CorpusClient{
+		entries: entries,
+		byTag:   make(map[string][]int),
+	}
+	for i, entry := range entries {
+		for _, tag := range entry.Tags {
+			tag = normalizeTag(tag)
+			if tag == "" {
+				continue
+			}
+			if _, ok := c.byTag[tag]; !ok {
+				c.tags = append(c.tags, tag)
+			}
+			c.byTag[tag] = append(c.byTag[tag], i)
+		}
+	}
+	sort.Strings(c.tags)
+	return c
+}
+
+// LoadCorpusFile reads a corpus file, detecting the format from its extension
+func LoadCorpusFile(path string) ([]CorpusEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	if format == "yml" {
+		format = CorpusFormatYAML
+	}
+	return ParseCorpus(data, format)
+}
+
+// ParseCorpus decodes corpus entries in the given format.
+// CSV files have the columns text, author and tags, with tags separated by "|".
+func ParseCorpus(data []byte, format string) ([]CorpusEntry, error) {
+	var entries []CorpusEntry
+
+	switch format {
+	case CorpusFormatJSON:
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, err
+		}
+	case CorpusFormatYAML:
+		if err := yaml.Unmarshal(data, &entries); err != nil {
+			return nil, err
+		}
+	case CorpusFormatCSV:
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.FieldsPerRecord = 3
+		for line := 1; ; line++ {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			// Skip the optional header row
+			if line == 1 && strings.EqualFold(record[0], "text") {
+				continue
+			}
+			entries = append(entries, CorpusEntry{
+				Text:   record[0],
+				Author: record[1],
+				Tags:   strings.Split(record[2], "|"),
+			})
+		}
+	default:
+		return nil, fmt.Errorf("unsupported corpus format %q", format)
+	}
+
+	// Drop entries that cannot be served
+	valid := entries[:0]
+	for _, entry := range entries {
+		entry.Text = strings.TrimSpace(entry.Text)
+		entry.Author = strings.TrimSpace(entry.Author)
+		if entry.Text == "" {
+			continue
+		}
+		valid = append(valid, entry)
+	}
+	return valid, nil
+}
+
+// Name returns the provider identifier
+func (c *CorpusClient) Name() string {
+	return ProviderCorpus
+}
+
+// GenerateQuote picks a corpus quote for the given tag
+func (c *CorpusClient) GenerateQuote(ctx context.Context, tag string, opts GenerateOptions) (*GenerationResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	candidates := c.candidates(tag)
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no corpus quotes available")
+	}
+
+	entry := c.entries[candidates[rand.Intn(len(candidates))]]
+	result := &GenerationResult{
+		Text:   entry.Text,
+		Source: c.Name(),
+	}
+	if entry.Author != "" {
+		author := entry.Author
+		result.Author = &author
+	}
+	return result, nil
+}
+
+// MatchTag returns the corpus tag closest to the given tag, or "" if none is close
+func (c *CorpusClient) MatchTag(tag string) string {
+	tag = normalizeTag(tag)
+	if tag == "" {
+		return ""
+	}
+	if _, ok := c.byTag[tag]; ok {
+		return tag
+	}
+
+	// Prefer tags sharing a stem, e.g. "joyful" -> "joy" or "angry" -> "anger"
+	best, bestPrefix := "", 0
+	for _, known := range c.tags {
+		prefix := commonPrefixLen(tag, known)
+		shorter := len(tag)
+		if len(known) < shorter {
+			shorter = len(known)
+		}
+		if prefix >= 3 && prefix*2 >= shorter && prefix > bestPrefix {
+			best, bestPrefix = known, prefix
+		}
+	}
+	if best != "" {
+		return best
+	}
+
+	// Fall back to the closest tag by edit distance to absorb typos
+	threshold := len(tag) / 3
+	if threshold < 1 {
+		threshold = 1
+	}
+	bestDist := threshold + 1
+	for _, known := range c.tags {
+		if dist := levenshtein(tag, known); dist < bestDist {
+			best, bestDist = known, dist
+		}
+	}
+	return best
+}
+
+// candidates returns the indexes of entries suitable for the given tag
+func (c *CorpusClient) candidates(tag string) []int {
+	if match := c.MatchTag(tag); match != "" {
+		return c.byTag[match]
+	}
+
+	// Look for the tag in the quote text itself
+	needle := normalizeTag(tag)
+	var matches []int
+	if needle != "" {
+		for i, entry := range c.entries {
+			if strings.Contains(strings.ToLower(entry.Text), needle) {
+				matches = append(matches, i)
+			}
+		}
+	}
+	if len(matches) > 0 {
+		return matches
+	}
+
+	// Nothing related, serve any quote
+	all := make([]int, len(c.entries))
+	for i := range all {
+		all[i] = i
+	}
+	return all
+}
+
+// normalizeTag lowercases and trims a tag
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// commonPrefixLen returns the length of the common prefix of a and b
+func commonPrefixLen(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// levenshtein returns the edit distance between a and b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, minInt(curr[j-1]+1, prev[j-1]+cost))
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// minInt returns the smaller of a and b
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,244 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Provider names accepted by QUOTE_PROVIDER and stored in Quote.Source
+const (
+	ProviderOpenRouter = "openrouter"
+	ProviderOllama     = "ollama"
+	ProviderCorpus     = "corpus"
+)
+
+// QuoteGenerator is implemented by every quote generation backend
+type QuoteGenerator interface {
+	// Name returns the provider identifier
+	Name() string
+	// GenerateQuote generates a quote for the given tag, giving up when ctx is done
+	GenerateQuote(ctx context.Context, tag string, opts GenerateOptions) (*GenerationResult, error)
+}
+
+// GenerateOptions holds per-request generation settings
+type GenerateOptions struct {
+	Temperature float64
+	MaxTokens   int
+	// Tone, Length, Language and Audience are optional and validated against
+	// the allow-lists in the models package before they reach a provider
+	Tone     string
+	Length   string
+	Language string
+	Audience string
+	// Model selects an OpenRouter model from the allow-list; other providers ignore it
+	Model string
+	// Candidates asks for several quotes in one upstream call, returned as
+	// GenerationResult.Alternatives; providers without multiple choices ignore it
+	Candidates int
+}
+
+// withDefaults fills in zero-valued options
+func (o GenerateOptions) withDefaults() GenerateOptions {
+	if o.Temperature == 0 {
+		o.Temperature = 0.8
+	}
+	if o.MaxTokens == 0 {
+		o.MaxTokens = 150
+	}
+	return o
+}
+
+// Usage holds the token usage reported by a provider
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+	// Cost is the USD cost reported by OpenRouter usage accounting
+	Cost *float64 `json:"cost,omitempty"`
+}
+
+// GenerationResult is the outcome of a successful generation
+type GenerationResult struct {
+	Text        string
+	Author      *string
+	Language    *string
+	Explanation *string
+	Model       string
+	Source      string
+	Usage       Usage
+	CostUSD     float64
+	// PromptVersion identifies the prompt template used, empty for non-LLM providers
+	PromptVersion string
+	// Alternatives are the further candidates of a multi-candidate call. Their
+	// usage is included in this result's.
+	Alternatives []*GenerationResult
+	// Shared marks a copy of the quote handed to another request waiting on
+	// the same generation; its usage is accounted there and is zero here
+	Shared bool
+}
+
+// add adds the tokens and cost of other to the usage
+func (u *Usage) add(other Usage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+	if other.Cost != nil {
+		cost := *other.Cost
+		if u.Cost != nil {
+			cost += *u.Cost
+		}
+		u.Cost = &cost
+	}
+}
+
+// addUsage adds usage spent on other attempts to the result
+func (r *GenerationResult) addUsage(usage Usage, costUSD float64) {
+	r.Usage.add(usage)
+	r.CostUSD += costUSD
+}
+
+// UsageError is returned by generations that failed after spending tokens, e.g.
+// when every generated quote was rejected. Wrappers replace it with one holding
+// their total, so the outermost UsageError carries the whole spend.
+type UsageError struct {
+	Err     error
+	Usage   Usage
+	CostUSD float64
+}
+
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// SpentUsage returns the usage spent by the failed generation that returned err
+func SpentUsage(err error) (Usage, float64) {
+	var usageErr *UsageError
+	if errors.As(err, &usageErr) {
+		return usageErr.Usage, usageErr.CostUSD
+	}
+	return Usage{}, 0
+}
+
+// withUsage wraps err with the usage spent before it failed, if any
+func withUsage(err error, usage Usage, costUSD float64) error {
+	if usage == (Usage{}) && costUSD == 0 {
+		return err
+	}
+	return &UsageError{Err: err, Usage: usage, CostUSD: costUSD}
+}
+
+// defaultLanguage records the requested language when the provider did not report one
+func (r *GenerationResult) defaultLanguage(language string) {
+	if r.Language == nil && language != "" {
+		r.Language = stringPtr(language)
+	}
+}
+
+// ConfiguredProviders returns the ordered provider names listed in QUOTE_PROVIDER
+func ConfiguredProviders() []string {
+	var providers []string
+	for _, name := range strings.Split(os.Getenv("QUOTE_PROVIDER"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" {
+			providers = append(providers, name)
+		}
+	}
+	if len(providers) == 0 {
+		providers = []string{ProviderOpenRouter}
+	}
+	return providers
+}
+
+// NewGenerator creates the provider chain configured by QUOTE_PROVIDER,
+// a comma-separated list such as "openrouter,ollama,corpus"
+func NewGenerator() (QuoteGenerator, error) {
+	return NewGeneratorFor(ConfiguredProviders())
+}
+
+// NewGeneratorFor creates a fallback chain of the named providers, in order
+func NewGeneratorFor(names []string) (QuoteGenerator, error) {
+	generators, err := NewGenerators(names)
+	if err != nil {
+		return nil, err
+	}
+	return generators[0], nil
+}
+
+// NewGenerators creates a fallback chain for each list of provider names. A
+// provider named in several lists is created once and shared by their chains,
+// so it has a single circuit breaker and status.
+func NewGenerators(chains ...[]string) ([]QuoteGenerator, error) {
+	breakerConfig := BreakerConfigFromEnv()
+	pipeline := NewPipeline(PipelineConfigFromEnv())
+	prompts, err := NewPromptRegistry()
+	if err != nil {
+		return nil, err
+	}
+	prices, err := NewPriceTable()
+	if err != nil {
+		return nil, err
+	}
+
+	built := make(map[string]QuoteGenerator)
+	generators := make([]QuoteGenerator, 0, len(chains))
+	for _, names := range chains {
+		seen := make(map[string]bool)
+		var providers []QuoteGenerator
+		for _, name := range names {
+			if seen[name] {
+				return nil, fmt.Errorf("quote provider %q is listed more than once", name)
+			}
+			seen[name] = true
+
+			provider, ok := built[name]
+			if !ok {
+				if provider, err = newProvider(name, prompts, prices); err != nil {
+					return nil, err
+				}
+
+				// Every provider's output is normalised and validated before the breaker
+				// sees it; rejected quotes do not count as upstream failures
+				provider = WithPipeline(provider, pipeline)
+
+				// Only upstream LLM calls can hang or fail repeatedly
+				if name != ProviderCorpus && breakerConfig.FailureThreshold > 0 {
+					provider = WithCircuitBreaker(provider, breakerConfig)
+				}
+				built[name] = provider
+			}
+			providers = append(providers, provider)
+		}
+		if len(providers) == 0 {
+			return nil, fmt.Errorf("no quote providers configured")
+		}
+		generators = append(generators, NewFallbackGenerator(providers...))
+	}
+	return generators, nil
+}
+
+// newProvider creates a single provider by name
+func newProvider(name string, prompts *PromptRegistry, prices *PriceTable) (QuoteGenerator, error) {
+	switch name {
+	case ProviderOpenRouter:
+		c := NewOpenRouterClient()
+		c.Prompts = prompts
+		c.Prices = prices
+		return c, nil
+	case ProviderOllama:
+		c := NewOllamaClient()
+		c.Prompts = prompts
+		c.Prices = prices
+		return c, nil
+	case ProviderCorpus:
+		return NewCorpusClient()
+	default:
+		return nil, fmt.Errorf("unknown quote provider %q", name)
+	}
+}
@@ -0,0 +1,275 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Protocols accepted by OLLAMA_API
+const (
+	OllamaAPIChat     = "chat"     // Ollama /api/chat
+	OllamaAPIGenerate = "generate" // Ollama /api/generate
+	OllamaAPIOpenAI   = "openai"   // llama.cpp server /v1/chat/completions
+)
+
+// OllamaClient handles API calls to a local Ollama or llama.cpp server
+type OllamaClient struct {
+	BaseURL    string
+	Model      string
+	API        string
+	HTTPClient *http.Client
+	Prompts    *PromptRegistry
+	Prices     *PriceTable
+}
+
+// NewOllamaClient creates a new local LLM client
+func NewOllamaClient() *OllamaClient {
+	baseURL := os.Getenv("OLLAMA_BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:11434"
+	}
+
+	model := os.Getenv("OLLAMA_MODEL")
+	if model == "" {
+		model = "llama3.2"
+	}
+
+	api := strings.ToLower(os.Getenv("OLLAMA_API"))
+	switch api {
+	case OllamaAPIChat, OllamaAPIGenerate, OllamaAPIOpenAI:
+	case "":
+		api = OllamaAPIChat
+	default:
+		log.Printf("Warning: unknown OLLAMA_API %q, using %q", api, OllamaAPIChat)
+		api = OllamaAPIChat
+	}
+
+	timeout := 60 * time.Second
+	if value := os.Getenv("OLLAMA_TIMEOUT"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			timeout = parsed
+		} else {
+			log.Printf("Warning: invalid OLLAMA_TIMEOUT %q, using %s", value, timeout)
+		}
+	}
+
+	return &OllamaClient{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Model:   model,
+		API:     api,
+		HTTPClient: &http.Client{
+			Timeout: timeout,
+		},
+		Prompts: EmbeddedPrompts(),
+		Prices:  EmbeddedPrices(),
+	}
+}
+
+// ollamaOptions holds Ollama model parameters
+type ollamaOptions struct {
+	Temperature float64 `json:"temperature"`
+	NumPredict  int     `json:"num_predict"`
+}
+
+// ollamaChatRequest represents the request to /api/chat
+type ollamaChatRequest struct {
+	Model    string        `json:"model"`
+	Messages []Message     `json:"messages"`
+	Stream   bool          `json:"stream"`
+	Options  ollamaOptions `json:"options"`
+}
+
+// ollamaGenerateRequest represents the request to /api/generate
+type ollamaGenerateRequest struct {
+	Model   string        `json:"model"`
+	System  string        `json:"system"`
+	Prompt  string        `json:"prompt"`
+	Stream  bool          `json:"stream"`
+	Options ollamaOptions `json:"options"`
+}
+
+// ollamaResponse represents the response from /api/chat and /api/generate
+type ollamaResponse struct {
+	Model           string   `json:"model"`
+	Message         *Message `json:"message,omitempty"`
+	Response        string   `json:"response"`
+	PromptEvalCount int      `json:"prompt_eval_count"`
+	EvalCount       int      `json:"eval_count"`
+	Error           string   `json:"error,omitempty"`
+}
+
+// Name returns the provider identifier
+func (c *OllamaClient) Name() string {
+	return ProviderOllama
+}
+
+// GenerateQuote generates a quote for the given tag
+func (c *OllamaClient) GenerateQuote(ctx context.Context, tag string, opts GenerateOptions) (*GenerationResult, error) {
+	opts = opts.withDefaults()
+	options := ollamaOptions{
+		Temperature: opts.Temperature,
+		NumPredict:  opts.MaxTokens,
+	}
+
+	prompt, err := c.Prompts.Render(newPromptData(tag, opts, false))
+	if err != nil {
+		return nil, err
+	}
+
+	var result *GenerationResult
+	switch c.API {
+	case OllamaAPIGenerate:
+		result, err = c.generate(ctx, ollamaGenerateRequest{
+			Model:   c.Model,
+			System:  prompt.System,
+			Prompt:  prompt.User,
+			Options: options,
+		})
+	case OllamaAPIOpenAI:
+		result, err = c.chatCompletion(ctx, ChatCompletionRequest{
+			Model:       c.Model,
+			Messages:    prompt.Messages(),
+			Temperature: opts.Temperature,
+			MaxTokens:   opts.MaxTokens,
+		})
+	default:
+		result, err = c.chat(ctx, ollamaChatRequest{
+			Model:    c.Model,
+			Messages: prompt.Messages(),
+			Options:  options,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.Text = strings.TrimSpace(result.Text)
+	log.Printf("Successfully generated quote: %s", result.Text)
+
+	result.Source = c.Name()
+	result.PromptVersion = prompt.Version
+	result.defaultLanguage(opts.Language)
+	if result.Model == "" {
+		result.Model = c.Model
+	}
+	result.applyCost(c.Prices)
+	return result, nil
+}
+
+// chat calls the Ollama /api/chat endpoint
+func (c *OllamaClient) chat(ctx context.Context, request ollamaChatRequest) (*GenerationResult, error) {
+	var response ollamaResponse
+	if err := c.post(ctx, "/api/chat", request, &response); err != nil {
+		return nil, err
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("API error: %s", response.Error)
+	}
+	if response.Message == nil {
+		return nil, fmt.Errorf("no message returned from API")
+	}
+
+	return &GenerationResult{
+		Text:  response.Message.Content,
+		Model: response.Model,
+		Usage: ollamaUsage(response),
+	}, nil
+}
+
+// generate calls the Ollama /api/generate endpoint
+func (c *OllamaClient) generate(ctx context.Context, request ollamaGenerateRequest) (*GenerationResult, error) {
+	var response ollamaResponse
+	if err := c.post(ctx, "/api/generate", request, &response); err != nil {
+		return nil, err
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("API error: %s", response.Error)
+	}
+
+	return &GenerationResult{
+		Text:  response.Response,
+		Model: response.Model,
+		Usage: ollamaUsage(response),
+	}, nil
+}
+
+// chatCompletion calls the OpenAI-compatible endpoint served by llama.cpp
+func (c *OllamaClient) chatCompletion(ctx context.Context, request ChatCompletionRequest) (*GenerationResult, error) {
+	var response ChatCompletionResponse
+	if err := c.post(ctx, "/v1/chat/completions", request, &response); err != nil {
+		return nil, err
+	}
+	if response.Error != nil {
+		return nil, fmt.Errorf("API error: %s", response.Error.Message)
+	}
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("no choices returned from API")
+	}
+
+	result := &GenerationResult{
+		Text:  response.Choices[0].Message.Content,
+		Model: response.Model,
+	}
+	if response.Usage != nil {
+		result.Usage = *response.Usage
+	}
+	return result, nil
+}
+
+// post sends a JSON request to the local server and decodes the response
+func (c *OllamaClient) post(ctx context.Context, path string, payload interface{}, out interface{}) error {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := c.BaseURL + path
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	log.Printf("Calling local LLM API: %s with model %s", url, c.Model)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("Local LLM API error: status=%d, body=%s", resp.StatusCode, string(body))
+		return &HTTPError{
+			StatusCode: resp.StatusCode,
+			Message:    string(body),
+		}
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// ollamaUsage converts Ollama eval counters to token usage
+func ollamaUsage(response ollamaResponse) Usage {
+	return Usage{
+		PromptTokens:     response.PromptEvalCount,
+		CompletionTokens: response.EvalCount,
+		TotalTokens:      response.PromptEvalCount + response.EvalCount,
+	}
+}
@@ -2,6 +2,7 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,8 +10,6 @@ import (
 	"net/http"
 	"os"
 	"time"
-
-	"github.com/Adeel56/quotebox/internal/metrics"
 )
 
 // OpenRouterClient handles API calls to OpenRouter
@@ -19,6 +18,11 @@ type OpenRouterClient struct {
 	Model      string
 	BaseURL    string
 	HTTPClient *http.Client
+	Retry      RetryPolicy
+	// ResponseFormat is text, json_object or json_schema
+	ResponseFormat string
+	Prompts        *PromptRegistry
+	Prices         *PriceTable
 }
 
 // NewOpenRouterClient creates a new OpenRouter client
@@ -28,24 +32,41 @@ func NewOpenRouterClient() *OpenRouterClient {
 		log.Fatal("OPENROUTER_API_KEY environment variable is required")
 	}
 
-	model := os.Getenv("OPENROUTER_MODEL")
-	if model == "" {
-		model = "openrouter/auto"
-	}
-
 	baseURL := os.Getenv("OPENROUTER_BASE_URL")
 	if baseURL == "" {
 		baseURL = "https://openrouter.ai/api/v1"
 	}
 
+	responseFormat := os.Getenv("OPENROUTER_RESPONSE_FORMAT")
+	switch responseFormat {
+	case ResponseFormatText, ResponseFormatJSONObject, ResponseFormatJSONSchema:
+	case "":
+		responseFormat = ResponseFormatJSONObject
+	default:
+		log.Printf("Warning: unknown OPENROUTER_RESPONSE_FORMAT %q, using %q", responseFormat, ResponseFormatJSONObject)
+		responseFormat = ResponseFormatJSONObject
+	}
+
 	return &OpenRouterClient{
 		APIKey:  apiKey,
-		Model:   model,
+		Model:   defaultOpenRouterModel(),
 		BaseURL: baseURL,
 		HTTPClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		Retry:          RetryPolicyFromEnv("OPENROUTER"),
+		ResponseFormat: responseFormat,
+		Prompts:        EmbeddedPrompts(),
+		Prices:         EmbeddedPrices(),
+	}
+}
+
+// defaultOpenRouterModel returns OPENROUTER_MODEL or the OpenRouter auto router
+func defaultOpenRouterModel() string {
+	if model := os.Getenv("OPENROUTER_MODEL"); model != "" {
+		return model
 	}
+	return "openrouter/auto"
 }
 
 // ChatCompletionRequest represents the request to OpenRouter API
@@ -54,6 +75,18 @@ type ChatCompletionRequest struct {
 	Messages    []Message `json:"messages"`
 	Temperature float64   `json:"temperature"`
 	MaxTokens   int       `json:"max_tokens"`
+	Stream      bool      `json:"stream,omitempty"`
+	// N requests several choices in one call
+	N int `json:"n,omitempty"`
+	// ResponseFormat requests JSON output from models that support it
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	// Usage asks OpenRouter to report the cost of the request
+	Usage *UsageOptions `json:"usage,omitempty"`
+}
+
+// UsageOptions enables OpenRouter usage accounting
+type UsageOptions struct {
+	Include bool `json:"include"`
 }
 
 // Message represents a chat message
@@ -64,8 +97,10 @@ type Message struct {
 
 // ChatCompletionResponse represents the response from OpenRouter API
 type ChatCompletionResponse struct {
-	ID      string   `json:"id"`
-	Choices []Choice `json:"choices"`
+	ID      string    `json:"id"`
+	Model   string    `json:"model"`
+	Choices []Choice  `json:"choices"`
+	Usage   *Usage    `json:"usage,omitempty"`
 	Error   *APIError `json:"error,omitempty"`
 }
 
@@ -82,135 +117,200 @@ type APIError struct {
 	Code    string `json:"code"`
 }
 
+// Name returns the provider identifier
+func (c *OpenRouterClient) Name() string {
+	return ProviderOpenRouter
+}
+
 // GenerateQuote generates a quote for the given tag
-func (c *OpenRouterClient) GenerateQuote(tag string) (string, error) {
-	prompt := fmt.Sprintf(
-		"Generate a meaningful inspirational quote about %s. "+
-			"The quote should be 1-2 sentences, insightful, and motivational. "+
-			"Only return the quote text itself without any introduction or explanation.",
-		tag,
-	)
+func (c *OpenRouterClient) GenerateQuote(ctx context.Context, tag string, opts GenerateOptions) (*GenerationResult, error) {
+	opts = opts.withDefaults()
+
+	responseFormat := newResponseFormat(c.ResponseFormat)
+	prompt, err := c.Prompts.Render(newPromptData(tag, opts, responseFormat != nil))
+	if err != nil {
+		return nil, err
+	}
 
 	request := ChatCompletionRequest{
-		Model: c.Model,
-		Messages: []Message{
-			{
-				Role:    "system",
-				Content: "You are a wise philosopher who creates short, meaningful quotes. Always respond with only the quote text, nothing else.",
-			},
-			{
-				Role:    "user",
-				Content: prompt,
-			},
-		},
-		Temperature: 0.8,
-		MaxTokens:   150,
+		Model:          c.model(opts),
+		Messages:       prompt.Messages(),
+		Temperature:    opts.Temperature,
+		MaxTokens:      opts.MaxTokens,
+		ResponseFormat: responseFormat,
+		Usage:          &UsageOptions{Include: true},
+	}
+	if opts.Candidates > 1 {
+		request.N = opts.Candidates
+	}
+
+	result, err := c.withRetry(ctx, func() (*GenerationResult, error) {
+		return c.makeRequest(ctx, request)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.PromptVersion = prompt.Version
+	result.defaultLanguage(opts.Language)
+	result.applyCost(c.Prices)
+	for _, alternative := range result.Alternatives {
+		alternative.PromptVersion = prompt.Version
+		alternative.defaultLanguage(opts.Language)
+	}
+	return result, nil
+}
+
+// model returns the model requested in opts, or the client's default
+func (c *OpenRouterClient) model(opts GenerateOptions) string {
+	if opts.Model != "" {
+		return opts.Model
 	}
+	return c.Model
+}
 
-	// Try the request, with one retry on transient errors
+// withRetry runs call, retrying transient errors within the retry budget
+func (c *OpenRouterClient) withRetry(ctx context.Context, call func() (*GenerationResult, error)) (*GenerationResult, error) {
+	start := time.Now()
 	var lastErr error
-	for attempt := 0; attempt < 2; attempt++ {
-		if attempt > 0 {
-			log.Printf("Retrying OpenRouter API call (attempt %d)...", attempt+1)
-			time.Sleep(500 * time.Millisecond)
+	for attempt := 1; attempt <= c.Retry.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			delay := c.Retry.Delay(attempt-1, lastErr)
+			if c.Retry.Budget > 0 && time.Since(start)+delay > c.Retry.Budget {
+				log.Printf("OpenRouter retry budget of %s exhausted", c.Retry.Budget)
+				break
+			}
+			log.Printf("Retrying OpenRouter API call (attempt %d) in %s...", attempt, delay)
+			if err := sleepContext(ctx, delay); err != nil {
+				return nil, err
+			}
 		}
 
-		quote, err := c.makeRequest(request)
+		result, err := call()
 		if err == nil {
-			metrics.SetOpenRouterStatus(true)
-			return quote, nil
+			return result, nil
 		}
 
 		lastErr = err
 
-		// Check if error is retryable (429, 5xx)
+		// Stop when the caller went away or the deadline passed
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		// Check if error is retryable (429, 5xx, network errors)
 		if !isRetryableError(err) {
 			break
 		}
 	}
 
-	metrics.SetOpenRouterStatus(false)
-	return "", lastErr
+	return nil, lastErr
 }
 
-// makeRequest makes the actual HTTP request to OpenRouter
-func (c *OpenRouterClient) makeRequest(request ChatCompletionRequest) (string, error) {
+// send posts the request to OpenRouter and returns the response of a successful call.
+// The caller must close the response body.
+func (c *OpenRouterClient) send(ctx context.Context, request ChatCompletionRequest) (*http.Response, error) {
 	jsonData, err := json.Marshal(request)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	url := fmt.Sprintf("%s/chat/completions", c.BaseURL)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.APIKey))
 
-	log.Printf("Calling OpenRouter API: %s with model %s", url, c.Model)
+	log.Printf("Calling OpenRouter API: %s with model %s", url, request.Model)
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %w", err)
+		return nil, fmt.Errorf("request failed: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response body: %w", err)
+		}
+
 		log.Printf("OpenRouter API error: status=%d, body=%s", resp.StatusCode, string(body))
-		return "", &HTTPError{
+		return nil, &HTTPError{
 			StatusCode: resp.StatusCode,
 			Message:    string(body),
+			RetryAfter: ParseRetryAfter(resp.Header, time.Now()),
 		}
 	}
 
+	return resp, nil
+}
+
+// makeRequest makes the actual HTTP request to OpenRouter
+func (c *OpenRouterClient) makeRequest(ctx context.Context, request ChatCompletionRequest) (*GenerationResult, error) {
+	resp, err := c.send(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
 	var response ChatCompletionResponse
 	if err := json.Unmarshal(body, &response); err != nil {
-		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
 	if response.Error != nil {
-		return "", fmt.Errorf("API error: %s", response.Error.Message)
+		return nil, fmt.Errorf("API error: %s", response.Error.Message)
 	}
 
 	if len(response.Choices) == 0 {
-		return "", fmt.Errorf("no choices returned from API")
+		return nil, fmt.Errorf("no choices returned from API")
+	}
+
+	result := &GenerationResult{
+		Model:  response.Model,
+		Source: c.Name(),
+	}
+
+	// Models may ignore the requested JSON format, so fall back to plain text
+	result.applyContent(response.Choices[0].Message.Content)
+
+	log.Printf("Successfully generated quote: %s", result.Text)
+
+	if result.Model == "" {
+		result.Model = request.Model
+	}
+	if response.Usage != nil {
+		result.Usage = *response.Usage
 	}
 
-	quote := response.Choices[0].Message.Content
-	
-	// Validate quote is not empty or too short
-	if len(quote) < 10 {
-		log.Printf("Warning: Generated quote is too short or empty: '%s'", quote)
-		return "", fmt.Errorf("generated quote is invalid or too short")
+	// Further choices of a multi-candidate request
+	for _, choice := range response.Choices[1:] {
+		alternative := &GenerationResult{
+			Model:  result.Model,
+			Source: result.Source,
+		}
+		alternative.applyContent(choice.Message.Content)
+		result.Alternatives = append(result.Alternatives, alternative)
 	}
-	
-	log.Printf("Successfully generated quote: %s", quote)
 
-	return quote, nil
+	return result, nil
 }
 
 // HTTPError represents an HTTP error
 type HTTPError struct {
 	StatusCode int
 	Message    string
+	RetryAfter time.Duration
 }
 
 func (e *HTTPError) Error() string {
 	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Message)
 }
-
-// isRetryableError checks if an error is retryable
-func isRetryableError(err error) bool {
-	if httpErr, ok := err.(*HTTPError); ok {
-		// Retry on 429 (rate limit) and 5xx (server errors)
-		return httpErr.StatusCode == 429 || (httpErr.StatusCode >= 500 && httpErr.StatusCode < 600)
-	}
-	return false
-}
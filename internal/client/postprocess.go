@@ -0,0 +1,365 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Adeel56/quotebox/internal/metrics"
+)
+
+// Reasons a generated quote is rejected, also used as the quote_rejections_total label
+const (
+	RejectEmpty            = "empty"
+	RejectTooShort         = "too_short"
+	RejectTooLong          = "too_long"
+	RejectTooManySentences = "too_many_sentences"
+	RejectRefusal          = "refusal"
+)
+
+// RejectionError is returned when a generated quote fails validation
+type RejectionError struct {
+	Reason string
+	Text   string
+}
+
+func (e *RejectionError) Error() string {
+	return fmt.Sprintf("generated quote rejected: %s", e.Reason)
+}
+
+// PipelineConfig holds the validation limits applied to every generated quote
+type PipelineConfig struct {
+	MinLength    int
+	MaxLength    int
+	MaxSentences int
+	MaxAttempts  int
+}
+
+// PipelineConfigFromEnv reads QUOTE_MIN_LENGTH, QUOTE_MAX_LENGTH, QUOTE_MAX_SENTENCES
+// and QUOTE_VALIDATION_ATTEMPTS. A limit of 0 disables the corresponding check.
+func PipelineConfigFromEnv() PipelineConfig {
+	config := PipelineConfig{
+		MinLength:    10,
+		MaxLength:    300,
+		MaxSentences: 3,
+		MaxAttempts:  3,
+	}
+
+	readInt := func(key string, target *int, min int) {
+		value := os.Getenv(key)
+		if value == "" {
+			return
+		}
+		if parsed, err := strconv.Atoi(value); err == nil && parsed >= min {
+			*target = parsed
+		} else {
+			log.Printf("Warning: invalid %s %q, using %d", key, value, *target)
+		}
+	}
+	readInt("QUOTE_MIN_LENGTH", &config.MinLength, 0)
+	readInt("QUOTE_MAX_LENGTH", &config.MaxLength, 0)
+	readInt("QUOTE_MAX_SENTENCES", &config.MaxSentences, 0)
+	readInt("QUOTE_VALIDATION_ATTEMPTS", &config.MaxAttempts, 1)
+
+	return config
+}
+
+// Normalizer rewrites a generation result in place
+type Normalizer func(result *GenerationResult)
+
+// Pipeline normalises model output and validates the resulting quote
+type Pipeline struct {
+	Normalizers []Normalizer
+	Config      PipelineConfig
+}
+
+// NewPipeline creates a pipeline with the default normalisers
+func NewPipeline(config PipelineConfig) *Pipeline {
+	return &Pipeline{
+		Normalizers: []Normalizer{
+			StripMarkdown,
+			StripPreamble,
+			ExtractAuthor,
+			StripWrappers,
+		},
+		Config: config,
+	}
+}
+
+// Process normalises the result and returns a *RejectionError if it is not an acceptable quote
+func (p *Pipeline) Process(result *GenerationResult) error {
+	for _, normalize := range p.Normalizers {
+		normalize(result)
+	}
+	return p.Validate(result.Text)
+}
+
+// Validate checks a normalised quote against the configured limits
+func (p *Pipeline) Validate(text string) error {
+	reject := func(reason string) error {
+		return &RejectionError{Reason: reason, Text: text}
+	}
+
+	length := utf8.RuneCountInString(text)
+	switch {
+	case length == 0:
+		return reject(RejectEmpty)
+	case isRefusal(text):
+		return reject(RejectRefusal)
+	case p.Config.MinLength > 0 && length < p.Config.MinLength:
+		return reject(RejectTooShort)
+	case p.Config.MaxLength > 0 && length > p.Config.MaxLength:
+		return reject(RejectTooLong)
+	case p.Config.MaxSentences > 0 && countSentences(text) > p.Config.MaxSentences:
+		return reject(RejectTooManySentences)
+	}
+	return nil
+}
+
+var (
+	// markdownLinePrefix matches headings and blockquotes
+	markdownLinePrefix = regexp.MustCompile(`^\s*(?:#{1,6}\s+|>\s*)`)
+	// markdownBullet matches list bullets
+	markdownBullet = regexp.MustCompile(`^\s*(?:[*+-]\s+|\d+\.\s+)`)
+	// markdownEmphasis matches bold, italic and inline code markers
+	markdownEmphasis = regexp.MustCompile("\\*\\*|__|`+")
+	// preamblePattern matches introductions such as "Sure! Here's a quote about hope:"
+	preamblePattern = regexp.MustCompile(`(?i)^(?:(?:sure|certainly|of course|absolutely|okay|ok)[!,.]*\s*)?(?:(?:here(?:'s| is| are)|this is)\b[^:\n]*|quote):\s*`)
+	// authorPattern matches a trailing attribution such as `" — Seneca` after the end of the quote
+	authorPattern = regexp.MustCompile(`^(.*[.!?"'”’»])\s*(?:—|–|--|-|~)\s*(\p{Lu}[^"“”\n]{1,80})$`)
+	// sentenceEnd matches terminal punctuation followed by whitespace or the end of the text
+	sentenceEnd = regexp.MustCompile(`[.!?…]+["'”’»)]*(?:\s|$)`)
+)
+
+// wrapperPairs are the opening and closing characters stripped around a quote
+var wrapperPairs = [][2]string{
+	{`"`, `"`},
+	{"“", "”"},
+	{"'", "'"},
+	{"‘", "’"},
+	{"«", "»"},
+	{"*", "*"},
+	{"_", "_"},
+}
+
+// refusalPrefixes start answers where the model declined to write a quote
+var refusalPrefixes = []string{
+	"i can't",
+	"i cannot",
+	"i can not",
+	"i won't",
+	"i will not",
+	"i'm sorry",
+	"i am sorry",
+	"sorry,",
+	"i'm unable",
+	"i am unable",
+	"i'm not able",
+	"i am not able",
+	"unfortunately,",
+}
+
+// StripMarkdown removes code fences, headings, bullets and emphasis markers
+func StripMarkdown(result *GenerationResult) {
+	var lines []string
+	for _, line := range strings.Split(result.Text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			continue
+		}
+		line = markdownLinePrefix.ReplaceAllString(line, "")
+		// A dash on a later line introduces the attribution rather than a list item
+		if len(lines) == 0 {
+			line = markdownBullet.ReplaceAllString(line, "")
+		}
+		line = markdownEmphasis.ReplaceAllString(line, "")
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	result.Text = strings.Join(lines, "\n")
+}
+
+// StripPreamble removes introductions such as "Here's a quote:" before the quote
+func StripPreamble(result *GenerationResult) {
+	result.Text = strings.TrimSpace(preamblePattern.ReplaceAllString(result.Text, ""))
+}
+
+// ExtractAuthor moves a trailing "— Author" attribution out of the text and into
+// Author, keeping an author the provider already reported
+func ExtractAuthor(result *GenerationResult) {
+	text := strings.TrimSpace(result.Text)
+
+	var quote, author string
+	lines := strings.Split(text, "\n")
+	if last := lines[len(lines)-1]; len(lines) > 1 && strings.IndexAny(last, "—–-~") == 0 {
+		// Attribution on its own line
+		quote = strings.Join(lines[:len(lines)-1], "\n")
+		author = strings.TrimLeft(last, "—–-~ ")
+	} else if match := authorPattern.FindStringSubmatch(strings.Join(lines, " ")); match != nil {
+		quote, author = match[1], match[2]
+	}
+
+	author = strings.Trim(author, " *_")
+	if author != "" {
+		result.Text = quote
+		if result.Author == nil {
+			result.Author = stringPtr(author)
+		}
+	}
+
+	// The quote itself is a single paragraph
+	result.Text = strings.Join(strings.Fields(result.Text), " ")
+}
+
+// StripWrappers removes quotation marks and emphasis wrapped around the whole quote
+func StripWrappers(result *GenerationResult) {
+	text := strings.TrimSpace(result.Text)
+	for stripped := true; stripped; {
+		stripped = false
+		for _, pair := range wrapperPairs {
+			inner := strings.TrimSuffix(strings.TrimPrefix(text, pair[0]), pair[1])
+			if len(inner) == len(text)-len(pair[0])-len(pair[1]) && !strings.Contains(inner, pair[0]) {
+				text = strings.TrimSpace(inner)
+				stripped = true
+			}
+		}
+	}
+	result.Text = text
+}
+
+// isRefusal reports whether the text is the model declining the request
+func isRefusal(text string) bool {
+	lower := strings.ToLower(strings.ReplaceAll(text, "’", "'"))
+	if strings.Contains(lower, "as an ai") || strings.Contains(lower, "language model") {
+		return true
+	}
+	for _, prefix := range refusalPrefixes {
+		if strings.HasPrefix(lower, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// countSentences returns the number of sentences in the text
+func countSentences(text string) int {
+	count := len(sentenceEnd.FindAllStringIndex(text, -1))
+	// A final sentence without terminal punctuation still counts
+	trimmed := strings.TrimRight(text, `"'”’») `)
+	if last, _ := utf8.DecodeLastRuneInString(trimmed); trimmed != "" && !strings.ContainsRune(".!?…", last) {
+		count++
+	}
+	return count
+}
+
+// ValidatingGenerator runs the post-processing pipeline on a provider's output,
+// asking the provider again when the quote is rejected
+type ValidatingGenerator struct {
+	QuoteGenerator
+	Pipeline *Pipeline
+}
+
+// WithPipeline wraps a provider in the post-processing pipeline
+func WithPipeline(generator QuoteGenerator, pipeline *Pipeline) *ValidatingGenerator {
+	return &ValidatingGenerator{
+		QuoteGenerator: generator,
+		Pipeline:       pipeline,
+	}
+}
+
+// GenerateQuote generates quotes until one passes validation or the attempts run out.
+// The usage of rejected attempts is added to the accepted result, or returned
+// in a *UsageError when every attempt failed.
+func (g *ValidatingGenerator) GenerateQuote(ctx context.Context, tag string, opts GenerateOptions) (*GenerationResult, error) {
+	attempts := g.Pipeline.Config.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var spent Usage
+	var spentCost float64
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		result, err := g.QuoteGenerator.GenerateQuote(ctx, tag, opts)
+		if err != nil {
+			usage, cost := SpentUsage(err)
+			spent.add(usage)
+			return nil, withUsage(err, spent, spentCost+cost)
+		}
+
+		// processCandidates moves the usage to the accepted candidate
+		usage, cost := result.Usage, result.CostUSD
+		accepted, err := g.processCandidates(result)
+		if err == nil {
+			accepted.addUsage(spent, spentCost)
+			return accepted, nil
+		}
+		spent.add(usage)
+		spentCost += cost
+		lastErr = err
+		if err := ctx.Err(); err != nil {
+			return nil, withUsage(err, spent, spentCost)
+		}
+	}
+	return nil, withUsage(lastErr, spent, spentCost)
+}
+
+// StreamQuote streams from the wrapped provider and validates the complete quote.
+// Tokens have already reached the caller by then, so a rejection is not retried.
+func (g *ValidatingGenerator) StreamQuote(ctx context.Context, tag string, opts GenerateOptions, onToken TokenFunc) (*GenerationResult, error) {
+	result, err := StreamQuote(ctx, g.QuoteGenerator, tag, opts, onToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.process(result); err != nil {
+		return nil, withUsage(err, result.Usage, result.CostUSD)
+	}
+	return result, nil
+}
+
+// processCandidates runs the pipeline on a result and its alternatives, dropping
+// rejected candidates. The first accepted one is returned and carries the usage.
+func (g *ValidatingGenerator) processCandidates(result *GenerationResult) (*GenerationResult, error) {
+	candidates := append([]*GenerationResult{result}, result.Alternatives...)
+	result.Alternatives = nil
+
+	var accepted []*GenerationResult
+	var firstErr error
+	for _, candidate := range candidates {
+		if err := g.process(candidate); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		accepted = append(accepted, candidate)
+	}
+	if len(accepted) == 0 {
+		return nil, firstErr
+	}
+
+	first := accepted[0]
+	if first != result {
+		first.Usage, first.CostUSD = result.Usage, result.CostUSD
+	}
+	first.Alternatives = accepted[1:]
+	return first, nil
+}
+
+// process runs the pipeline and records rejections
+func (g *ValidatingGenerator) process(result *GenerationResult) error {
+	err := g.Pipeline.Process(result)
+
+	var rejection *RejectionError
+	if errors.As(err, &rejection) {
+		log.Printf("Warning: rejected quote from %s (%s): %q", g.Name(), rejection.Reason, rejection.Text)
+		metrics.RecordQuoteRejected(g.Name(), rejection.Reason)
+	}
+	return err
+}
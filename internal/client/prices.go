@@ -0,0 +1,111 @@
+package client
+
+import (
+	_ "embed"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed catalog/prices.yaml
+var defaultPrices []byte
+
+// ModelPrice is the price of a model in USD per million tokens
+type ModelPrice struct {
+	Prompt     float64 `yaml:"prompt" json:"prompt"`
+	Completion float64 `yaml:"completion" json:"completion"`
+}
+
+// PriceTable prices token usage per model
+type PriceTable struct {
+	prices map[string]ModelPrice
+}
+
+var (
+	embeddedPrices     *PriceTable
+	embeddedPricesOnce sync.Once
+)
+
+// EmbeddedPrices returns the price table bundled with the binary
+func EmbeddedPrices() *PriceTable {
+	embeddedPricesOnce.Do(func() {
+		var err error
+		embeddedPrices, err = ParsePriceTable(defaultPrices)
+		if err != nil {
+			panic(fmt.Sprintf("invalid embedded price table: %v", err))
+		}
+	})
+	return embeddedPrices
+}
+
+// NewPriceTable loads the price table in QUOTE_PRICE_TABLE, or the bundled one
+func NewPriceTable() (*PriceTable, error) {
+	path := os.Getenv("QUOTE_PRICE_TABLE")
+	if path == "" {
+		return EmbeddedPrices(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read price table: %w", err)
+	}
+	table, err := ParsePriceTable(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid price table %s: %w", path, err)
+	}
+	log.Printf("Loaded prices for %d models from %s", len(table.prices), path)
+	return table, nil
+}
+
+// ParsePriceTable decodes a YAML (or JSON) map of model ids to prices
+func ParsePriceTable(data []byte) (*PriceTable, error) {
+	prices := make(map[string]ModelPrice)
+	if err := yaml.Unmarshal(data, &prices); err != nil {
+		return nil, err
+	}
+	for model, price := range prices {
+		if price.Prompt < 0 || price.Completion < 0 {
+			return nil, fmt.Errorf("negative price for model %q", model)
+		}
+	}
+	return &PriceTable{prices: prices}, nil
+}
+
+// Price returns the price of a model, matching the longest priced prefix of its id
+func (t *PriceTable) Price(model string) (ModelPrice, bool) {
+	if price, ok := t.prices[model]; ok {
+		return price, true
+	}
+
+	best, found := "", false
+	for id := range t.prices {
+		if strings.HasPrefix(model, id) && len(id) > len(best) {
+			best, found = id, true
+		}
+	}
+	return t.prices[best], found
+}
+
+// Cost returns the USD cost of the usage, or 0 for unpriced models
+func (t *PriceTable) Cost(model string, usage Usage) float64 {
+	price, ok := t.Price(model)
+	if !ok {
+		return 0
+	}
+	return (float64(usage.PromptTokens)*price.Prompt + float64(usage.CompletionTokens)*price.Completion) / 1e6
+}
+
+// applyCost sets the result's cost, preferring the cost reported by the provider
+func (r *GenerationResult) applyCost(prices *PriceTable) {
+	if r.Usage.Cost != nil {
+		r.CostUSD = *r.Usage.Cost
+		return
+	}
+	if prices != nil {
+		r.CostUSD = prices.Cost(r.Model, r.Usage)
+	}
+}
@@ -0,0 +1,212 @@
+package client
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/Adeel56/quotebox/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed prompts/*
+var promptsFS embed.FS
+
+// promptManifest is the file selecting which template version serves which tags
+const promptManifest = "manifest.yaml"
+
+// PromptData is passed to the prompt templates
+type PromptData struct {
+	Tag        string
+	TagSource  string
+	Structured bool
+	Tone       string
+	Length     string
+	Audience   string
+	// Language is the English name of the requested language, e.g. "French"
+	Language string
+}
+
+// newPromptData returns the template data for the given tag and options
+func newPromptData(tag string, opts GenerateOptions, structured bool) PromptData {
+	return PromptData{
+		Tag:        tag,
+		TagSource:  models.GetTagSource(tag),
+		Structured: structured,
+		Tone:       opts.Tone,
+		Length:     opts.Length,
+		Audience:   opts.Audience,
+		Language:   models.Languages[opts.Language],
+	}
+}
+
+// Prompt is a rendered prompt and the template version it came from
+type Prompt struct {
+	Version string
+	System  string
+	User    string
+}
+
+// Messages returns the prompt as chat messages
+func (p *Prompt) Messages() []Message {
+	return []Message{
+		{
+			Role:    "system",
+			Content: p.System,
+		},
+		{
+			Role:    "user",
+			Content: p.User,
+		},
+	}
+}
+
+// PromptManifest selects a template version per tag and per tag source
+type PromptManifest struct {
+	Default string            `yaml:"default"`
+	Sources map[string]string `yaml:"sources"`
+	Tags    map[string]string `yaml:"tags"`
+}
+
+// PromptRegistry holds versioned prompt templates. Each template defines a
+// "system" and a "user" template; files starting with "_" hold shared partials.
+type PromptRegistry struct {
+	templates map[string]*template.Template
+	manifest  PromptManifest
+}
+
+var (
+	embeddedPrompts     *PromptRegistry
+	embeddedPromptsOnce sync.Once
+)
+
+// EmbeddedPrompts returns the registry of prompt templates bundled with the binary
+func EmbeddedPrompts() *PromptRegistry {
+	embeddedPromptsOnce.Do(func() {
+		prompts, err := fs.Sub(promptsFS, "prompts")
+		if err == nil {
+			embeddedPrompts, err = LoadPromptRegistry(prompts)
+		}
+		if err != nil {
+			panic(fmt.Sprintf("invalid embedded prompt templates: %v", err))
+		}
+	})
+	return embeddedPrompts
+}
+
+// NewPromptRegistry loads the templates in QUOTE_PROMPT_DIR, or the bundled ones.
+// QUOTE_PROMPT_VERSION overrides the manifest default, e.g. to roll back a revision.
+func NewPromptRegistry() (*PromptRegistry, error) {
+	registry := EmbeddedPrompts()
+	if dir := os.Getenv("QUOTE_PROMPT_DIR"); dir != "" {
+		var err error
+		registry, err = LoadPromptRegistry(os.DirFS(dir))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load prompt templates from %s: %w", dir, err)
+		}
+		log.Printf("Loaded %d prompt templates from %s", len(registry.templates), dir)
+	}
+
+	if version := os.Getenv("QUOTE_PROMPT_VERSION"); version != "" {
+		if _, ok := registry.templates[version]; !ok {
+			return nil, fmt.Errorf("unknown QUOTE_PROMPT_VERSION %q", version)
+		}
+		pinned := *registry
+		pinned.manifest.Default = version
+		registry = &pinned
+	}
+
+	return registry, nil
+}
+
+// LoadPromptRegistry parses the manifest and *.tmpl templates in fsys
+func LoadPromptRegistry(fsys fs.FS) (*PromptRegistry, error) {
+	data, err := fs.ReadFile(fsys, promptManifest)
+	if err != nil {
+		return nil, err
+	}
+	var manifest PromptManifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", promptManifest, err)
+	}
+
+	files, err := fs.Glob(fsys, "*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	var partials, versions []string
+	for _, file := range files {
+		if strings.HasPrefix(file, "_") {
+			partials = append(partials, file)
+		} else {
+			versions = append(versions, file)
+		}
+	}
+
+	registry := &PromptRegistry{
+		templates: make(map[string]*template.Template),
+		manifest:  manifest,
+	}
+	for _, file := range versions {
+		version := strings.TrimSuffix(path.Base(file), ".tmpl")
+		tmpl, err := template.New(version).Option("missingkey=error").ParseFS(fsys, append(partials, file)...)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range []string{"system", "user"} {
+			if tmpl.Lookup(name) == nil {
+				return nil, fmt.Errorf("prompt template %s does not define %q", file, name)
+			}
+		}
+		registry.templates[version] = tmpl
+	}
+
+	// Every version the manifest refers to must exist
+	referenced := []string{manifest.Default}
+	for _, version := range manifest.Sources {
+		referenced = append(referenced, version)
+	}
+	for _, version := range manifest.Tags {
+		referenced = append(referenced, version)
+	}
+	for _, version := range referenced {
+		if _, ok := registry.templates[version]; !ok {
+			return nil, fmt.Errorf("%s refers to unknown prompt template %q", promptManifest, version)
+		}
+	}
+
+	return registry, nil
+}
+
+// Version returns the template version selected for a tag and tag source
+func (r *PromptRegistry) Version(tag, tagSource string) string {
+	if version, ok := r.manifest.Tags[strings.ToLower(tag)]; ok {
+		return version
+	}
+	if version, ok := r.manifest.Sources[tagSource]; ok {
+		return version
+	}
+	return r.manifest.Default
+}
+
+// Render renders the prompt selected for the data's tag
+func (r *PromptRegistry) Render(data PromptData) (*Prompt, error) {
+	version := r.Version(data.Tag, data.TagSource)
+	tmpl := r.templates[version]
+
+	prompt := &Prompt{Version: version}
+	for name, out := range map[string]*string{"system": &prompt.System, "user": &prompt.User} {
+		var text strings.Builder
+		if err := tmpl.ExecuteTemplate(&text, name, data); err != nil {
+			return nil, fmt.Errorf("failed to render prompt %s: %w", version, err)
+		}
+		*out = strings.TrimSpace(text.String())
+	}
+	return prompt, nil
+}
@@ -0,0 +1,162 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how failed upstream calls are retried
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one
+	MaxAttempts int
+	// BaseDelay is the backoff ceiling for the first retry, doubled on every attempt
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff ceiling
+	MaxDelay time.Duration
+	// Budget is the total time allowed for all attempts and waits (0 = unlimited)
+	Budget time.Duration
+}
+
+// DefaultRetryPolicy returns the retry policy used when nothing is configured
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    10 * time.Second,
+		Budget:      20 * time.Second,
+	}
+}
+
+// RetryPolicyFromEnv reads the retry policy from <prefix>_RETRY_MAX_ATTEMPTS,
+// <prefix>_RETRY_BASE_DELAY, <prefix>_RETRY_MAX_DELAY and <prefix>_RETRY_BUDGET
+func RetryPolicyFromEnv(prefix string) RetryPolicy {
+	policy := DefaultRetryPolicy()
+
+	if value := os.Getenv(prefix + "_RETRY_MAX_ATTEMPTS"); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+			policy.MaxAttempts = parsed
+		} else {
+			log.Printf("Warning: invalid %s_RETRY_MAX_ATTEMPTS %q, using %d", prefix, value, policy.MaxAttempts)
+		}
+	}
+
+	durations := []struct {
+		name  string
+		value *time.Duration
+	}{
+		{"_RETRY_BASE_DELAY", &policy.BaseDelay},
+		{"_RETRY_MAX_DELAY", &policy.MaxDelay},
+		{"_RETRY_BUDGET", &policy.Budget},
+	}
+	for _, d := range durations {
+		value := os.Getenv(prefix + d.name)
+		if value == "" {
+			continue
+		}
+		if parsed, err := time.ParseDuration(value); err == nil && parsed >= 0 {
+			*d.value = parsed
+		} else {
+			log.Printf("Warning: invalid %s%s %q, using %s", prefix, d.name, value, *d.value)
+		}
+	}
+
+	return policy
+}
+
+// Backoff returns a full-jitter delay for the given retry (1 for the first retry):
+// a random duration between 0 and min(MaxDelay, BaseDelay*2^(retry-1))
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	ceiling := p.BaseDelay
+	for i := 1; i < retry && ceiling < p.MaxDelay; i++ {
+		ceiling *= 2
+	}
+	if p.MaxDelay > 0 && ceiling > p.MaxDelay {
+		ceiling = p.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// Delay returns how long to wait before the given retry after err,
+// preferring a server-provided Retry-After over the jittered backoff
+func (p RetryPolicy) Delay(retry int, err error) time.Duration {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) && httpErr.RetryAfter > 0 {
+		return httpErr.RetryAfter
+	}
+	return p.Backoff(retry)
+}
+
+// ParseRetryAfter extracts the wait requested by the server from the Retry-After
+// header (seconds or HTTP date) or the OpenRouter X-RateLimit-Reset header
+// (Unix milliseconds, only when X-RateLimit-Remaining is 0)
+func ParseRetryAfter(header http.Header, now time.Time) time.Duration {
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		if date, err := http.ParseTime(value); err == nil && date.After(now) {
+			return date.Sub(now)
+		}
+	}
+
+	if header.Get("X-RateLimit-Remaining") == "0" {
+		if value := header.Get("X-RateLimit-Reset"); value != "" {
+			if millis, err := strconv.ParseInt(value, 10, 64); err == nil {
+				if reset := time.UnixMilli(millis); reset.After(now) {
+					return reset.Sub(now)
+				}
+			}
+		}
+	}
+
+	return 0
+}
+
+// sleepContext waits for d or until ctx is done, returning ctx.Err() in the latter case
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// isRetryableError checks if an error is retryable
+func isRetryableError(err error) bool {
+	var permErr *permanentError
+	if errors.As(err, &permErr) {
+		return false
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		// Retry on 429 (rate limit) and 5xx (server errors)
+		return httpErr.StatusCode == 429 || (httpErr.StatusCode >= 500 && httpErr.StatusCode < 600)
+	}
+
+	// Network errors and timeouts, including *url.Error from http.Client.Do and
+	// the per-attempt http.Client.Timeout; cancellation by the caller is handled
+	// by withRetry before asking
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	// Connection dropped while reading the body
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,184 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// TokenFunc receives each chunk of generated text as it arrives.
+// Returning an error aborts the generation.
+type TokenFunc func(token string) error
+
+// StreamingGenerator is implemented by generators that can emit tokens as they are generated
+type StreamingGenerator interface {
+	QuoteGenerator
+	StreamQuote(ctx context.Context, tag string, opts GenerateOptions, onToken TokenFunc) (*GenerationResult, error)
+}
+
+// StreamQuote streams a quote from generator, or delivers the complete quote
+// as a single token when the generator cannot stream
+func StreamQuote(ctx context.Context, generator QuoteGenerator, tag string, opts GenerateOptions, onToken TokenFunc) (*GenerationResult, error) {
+	if streamer, ok := generator.(StreamingGenerator); ok {
+		return streamer.StreamQuote(ctx, tag, opts, onToken)
+	}
+
+	result, err := generator.GenerateQuote(ctx, tag, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := onToken(result.Text); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ChatCompletionChunk represents a streamed chunk from the OpenRouter API
+type ChatCompletionChunk struct {
+	ID      string        `json:"id"`
+	Model   string        `json:"model"`
+	Choices []ChunkChoice `json:"choices"`
+	Usage   *Usage        `json:"usage,omitempty"`
+	Error   *APIError     `json:"error,omitempty"`
+}
+
+// ChunkChoice represents the delta of a streamed completion choice
+type ChunkChoice struct {
+	Delta        Message `json:"delta"`
+	FinishReason *string `json:"finish_reason"`
+}
+
+// permanentError marks an error that must not be retried
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// StreamQuote streams a quote for the given tag from OpenRouter
+func (c *OpenRouterClient) StreamQuote(ctx context.Context, tag string, opts GenerateOptions, onToken TokenFunc) (*GenerationResult, error) {
+	opts = opts.withDefaults()
+
+	// Tokens are shown as they arrive, so the quote is requested as plain text
+	prompt, err := c.Prompts.Render(newPromptData(tag, opts, false))
+	if err != nil {
+		return nil, err
+	}
+
+	request := ChatCompletionRequest{
+		Model:       c.model(opts),
+		Messages:    prompt.Messages(),
+		Temperature: opts.Temperature,
+		MaxTokens:   opts.MaxTokens,
+		Stream:      true,
+		Usage:       &UsageOptions{Include: true},
+	}
+
+	result, err := c.withRetry(ctx, func() (*GenerationResult, error) {
+		return c.streamRequest(ctx, request, onToken)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.PromptVersion = prompt.Version
+	result.defaultLanguage(opts.Language)
+	result.applyCost(c.Prices)
+	return result, nil
+}
+
+// streamRequest reads the server-sent events of a streaming completion
+func (c *OpenRouterClient) streamRequest(ctx context.Context, request ChatCompletionRequest, onToken TokenFunc) (*GenerationResult, error) {
+	resp, err := c.send(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var (
+		text    strings.Builder
+		model   string
+		usage   *Usage
+		emitted bool
+	)
+
+	// Once tokens have reached the caller a retry would duplicate them
+	fail := func(err error) (*GenerationResult, error) {
+		if emitted {
+			return nil, &permanentError{err: err}
+		}
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		// Blank lines separate events and lines starting with ":" are keep-alive comments
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk ChatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fail(fmt.Errorf("failed to unmarshal stream chunk: %w", err))
+		}
+		if chunk.Error != nil {
+			return fail(fmt.Errorf("API error: %s", chunk.Error.Message))
+		}
+		if chunk.Model != "" {
+			model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+
+		token := chunk.Choices[0].Delta.Content
+		text.WriteString(token)
+		emitted = true
+		if err := onToken(token); err != nil {
+			return nil, &permanentError{err: err}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fail(fmt.Errorf("failed to read stream: %w", err))
+	}
+
+	quote := strings.TrimSpace(text.String())
+	if quote == "" {
+		return fail(fmt.Errorf("no content streamed from API"))
+	}
+
+	log.Printf("Successfully streamed quote: %s", quote)
+
+	result := &GenerationResult{
+		Text:   quote,
+		Model:  model,
+		Source: c.Name(),
+	}
+	if result.Model == "" {
+		result.Model = request.Model
+	}
+	if usage != nil {
+		result.Usage = *usage
+	}
+
+	return result, nil
+}
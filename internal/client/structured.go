@@ -0,0 +1,138 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Response formats accepted by OPENROUTER_RESPONSE_FORMAT
+const (
+	ResponseFormatText       = "text"
+	ResponseFormatJSONObject = "json_object"
+	ResponseFormatJSONSchema = "json_schema"
+)
+
+// originalAuthor is the author the model reports for quotes it wrote itself
+const originalAuthor = "original"
+
+// maxExplanationLength bounds the optional explanation returned by the model
+const maxExplanationLength = 500
+
+// languagePattern matches ISO 639 language codes with an optional region, e.g. "en" or "pt-BR"
+var languagePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z]{2,4})?$`)
+
+// ResponseFormat represents the response_format field of a chat completion request
+type ResponseFormat struct {
+	Type       string      `json:"type"`
+	JSONSchema *JSONSchema `json:"json_schema,omitempty"`
+}
+
+// JSONSchema represents a named JSON schema for structured outputs
+type JSONSchema struct {
+	Name   string                 `json:"name"`
+	Strict bool                   `json:"strict"`
+	Schema map[string]interface{} `json:"schema"`
+}
+
+// quoteSchema is the JSON schema of a structured quote
+var quoteSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"quote":       map[string]interface{}{"type": "string", "description": "The quote text"},
+		"author":      map[string]interface{}{"type": "string", "description": `Attributed author, or "original"`},
+		"language":    map[string]interface{}{"type": "string", "description": "ISO 639-1 language code"},
+		"explanation": map[string]interface{}{"type": "string", "description": "One short sentence of context"},
+	},
+	"required":             []string{"quote", "author", "language", "explanation"},
+	"additionalProperties": false,
+}
+
+// newResponseFormat returns the response_format for the configured mode, or nil for plain text
+func newResponseFormat(mode string) *ResponseFormat {
+	switch mode {
+	case ResponseFormatJSONObject:
+		return &ResponseFormat{Type: ResponseFormatJSONObject}
+	case ResponseFormatJSONSchema:
+		return &ResponseFormat{
+			Type: ResponseFormatJSONSchema,
+			JSONSchema: &JSONSchema{
+				Name:   "quote",
+				Strict: true,
+				Schema: quoteSchema,
+			},
+		}
+	default:
+		return nil
+	}
+}
+
+// StructuredQuote is the JSON object requested from the model
+type StructuredQuote struct {
+	Quote       string `json:"quote"`
+	Author      string `json:"author"`
+	Language    string `json:"language"`
+	Explanation string `json:"explanation"`
+}
+
+// ParseStructuredQuote decodes and validates a structured quote, tolerating markdown code fences
+func ParseStructuredQuote(content string) (*StructuredQuote, error) {
+	content = strings.TrimSpace(content)
+	content = strings.TrimPrefix(content, "```json")
+	content = strings.TrimPrefix(content, "```")
+	content = strings.TrimSuffix(content, "```")
+	content = strings.TrimSpace(content)
+
+	if !strings.HasPrefix(content, "{") {
+		return nil, fmt.Errorf("response is not a JSON object")
+	}
+
+	var structured StructuredQuote
+	if err := json.Unmarshal([]byte(content), &structured); err != nil {
+		return nil, fmt.Errorf("invalid structured quote: %w", err)
+	}
+
+	structured.Quote = strings.TrimSpace(structured.Quote)
+	structured.Author = strings.TrimSpace(structured.Author)
+	structured.Language = strings.TrimSpace(structured.Language)
+	structured.Explanation = strings.TrimSpace(structured.Explanation)
+
+	if structured.Quote == "" {
+		return nil, fmt.Errorf("structured quote has no quote field")
+	}
+	if structured.Language != "" && !languagePattern.MatchString(structured.Language) {
+		return nil, fmt.Errorf("structured quote has invalid language %q", structured.Language)
+	}
+	if len(structured.Explanation) > maxExplanationLength {
+		return nil, fmt.Errorf("structured quote explanation is too long")
+	}
+
+	return &structured, nil
+}
+
+// applyContent fills the result from the model output, using the structured
+// fields when the model honoured the JSON format and the raw text otherwise
+func (r *GenerationResult) applyContent(content string) {
+	structured, err := ParseStructuredQuote(content)
+	if err != nil {
+		r.Text = strings.TrimSpace(content)
+		return
+	}
+
+	r.Text = structured.Quote
+	if structured.Author != "" && !strings.EqualFold(structured.Author, originalAuthor) {
+		r.Author = stringPtr(structured.Author)
+	}
+	if structured.Language != "" {
+		r.Language = stringPtr(structured.Language)
+	}
+	if structured.Explanation != "" {
+		r.Explanation = stringPtr(structured.Explanation)
+	}
+}
+
+// stringPtr returns a pointer to a copy of s
+func stringPtr(s string) *string {
+	return &s
+}
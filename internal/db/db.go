@@ -1,20 +1,21 @@
 package db
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/Adeel56/quotebox/internal/models"
+	"github.com/Adeel56/quotebox/internal/metrics"
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
-// DB is the global database connection
-var DB *gorm.DB
-
 // Config holds database configuration
 type Config struct {
 	Host     string
@@ -25,8 +26,57 @@ type Config struct {
 	SSLMode  string
 }
 
-// InitDB initializes the database connection
-func InitDB() error {
+// sqliteScheme selects SQLite in DATABASE_URL, e.g. sqlite:///var/lib/quotebox.db
+const sqliteScheme = "sqlite://"
+
+// sqliteBusyTimeout makes SQLite wait for locks held by other connections
+const sqliteBusyTimeout = "_pragma=busy_timeout(5000)"
+
+// Connect opens the database and applies pending migrations unless
+// DB_AUTO_MIGRATE is false
+func Connect() (*gorm.DB, error) {
+	db, err := Open()
+	if err != nil {
+		return nil, err
+	}
+	if !autoMigrate() {
+		return db, nil
+	}
+
+	migrator, err := NewMigrator(db)
+	if err == nil {
+		var applied []Migration
+		applied, err = migrator.Up(context.Background())
+		for _, migration := range applied {
+			log.Printf("Applied migration %04d_%s", migration.Version, migration.Name)
+		}
+	}
+	if err != nil {
+		if sqlDB, dbErr := db.DB(); dbErr == nil {
+			sqlDB.Close()
+		}
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
+	}
+	return db, nil
+}
+
+// autoMigrate reads DB_AUTO_MIGRATE, which defaults to true
+func autoMigrate() bool {
+	value := os.Getenv("DB_AUTO_MIGRATE")
+	if value == "" {
+		return true
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Warning: invalid DB_AUTO_MIGRATE %q, using true", value)
+		return true
+	}
+	return enabled
+}
+
+// Open opens the database named by DATABASE_URL, or the Postgres database
+// described by the DB_* variables, without migrating it
+func Open() (*gorm.DB, error) {
 	var dsn string
 
 	// Try DATABASE_URL first
@@ -57,44 +107,49 @@ func InitDB() error {
 		},
 	)
 
-	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
+	pool := PoolConfigFromEnv()
+	db, err := openWithRetry(dsn, &gorm.Config{
 		Logger: gormLogger,
-	})
+	}, pool.ConnectTimeout)
 	if err != nil {
-		return fmt.Errorf("failed to connect to database: %w", err)
+		return nil, err
 	}
 
 	// Get underlying SQL DB for connection pool settings
-	sqlDB, err := DB.DB()
+	sqlDB, err := db.DB()
 	if err != nil {
-		return fmt.Errorf("failed to get database instance: %w", err)
+		return nil, fmt.Errorf("failed to get database instance: %w", err)
 	}
 
 	// Set connection pool settings
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-	sqlDB.SetConnMaxLifetime(time.Hour)
-
-	// Auto-migrate the schema
-	if err := DB.AutoMigrate(&models.Quote{}); err != nil {
-		return fmt.Errorf("failed to auto-migrate schema: %w", err)
+	pool.Apply(sqlDB)
+	if db.Dialector.Name() == sqlite.DriverName {
+		// SQLite serialises writes, and every connection to :memory: is a separate database
+		sqlDB.SetMaxOpenConns(1)
 	}
+	metrics.RegisterDBStats(sqlDB, "quotebox")
 
 	log.Println("Database connection established successfully")
-	return nil
+	return db, nil
 }
 
-// CloseDB closes the database connection
-func CloseDB() error {
-	if DB != nil {
-		sqlDB, err := DB.DB()
-		if err != nil {
-			return err
-		}
-		return sqlDB.Close()
+// dialector returns the GORM dialector for a DATABASE_URL or Postgres DSN
+func dialector(dsn string) gorm.Dialector {
+	if path, ok := strings.CutPrefix(dsn, sqliteScheme); ok {
+		return sqlite.Open(sqliteDSN(path))
+	}
+	return postgres.Open(dsn)
+}
+
+// sqliteDSN adds a busy timeout to a SQLite path unless it sets its own pragmas
+func sqliteDSN(path string) string {
+	if strings.Contains(path, "busy_timeout") {
+		return path
 	}
-	return nil
+	if strings.Contains(path, "?") {
+		return path + "&" + sqliteBusyTimeout
+	}
+	return path + "?" + sqliteBusyTimeout
 }
 
 // getEnvOrDefault returns environment variable value or default
@@ -104,17 +159,3 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
-
-// HealthCheck performs a database health check
-func HealthCheck() error {
-	if DB == nil {
-		return fmt.Errorf("database connection is nil")
-	}
-
-	sqlDB, err := DB.DB()
-	if err != nil {
-		return err
-	}
-
-	return sqlDB.Ping()
-}
@@ -0,0 +1,330 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// migrationLockID is the Postgres advisory lock held while migrating
+const migrationLockID = 0x71756f7465626f78 // "quotebox"
+
+// migrationTables creates the schema_migrations table of each dialect
+var migrationTables = map[string]string{
+	"postgres": `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL
+	)`,
+	sqlite.DriverName: `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME NOT NULL
+	)`,
+}
+
+// addColumnIfNotExists matches the ADD COLUMN IF NOT EXISTS statements that
+// SQLite lacks, capturing the table and the column
+var addColumnIfNotExists = regexp.MustCompile(`(?is)^\s*ALTER\s+TABLE\s+(\w+)\s+ADD\s+COLUMN\s+IF\s+NOT\s+EXISTS\s+(\w+)\b`)
+
+// Migration is a versioned schema change read from migrations/<dialect>/NNNN_name.{up,down}.sql
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether a migration has been applied
+type MigrationStatus struct {
+	Migration
+	// AppliedAt is nil for pending migrations
+	AppliedAt *time.Time
+}
+
+// appliedMigration is a row of schema_migrations
+type appliedMigration struct {
+	Version   int
+	Name      string
+	AppliedAt time.Time
+}
+
+// LoadMigrations returns the embedded migrations of a dialect ordered by version
+func LoadMigrations(dialect string) ([]Migration, error) {
+	dir := path.Join("migrations", dialect)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for database dialect %q", dialect)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("unexpected migration file %s", name)
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		prefix, label, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration file %s must be named NNNN_name.%s.sql", name, direction)
+		}
+
+		data, err := fs.ReadFile(migrationFiles, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		migration := byVersion[version]
+		if migration == nil {
+			migration = &Migration{Version: version, Name: label}
+			byVersion[version] = migration
+		} else if migration.Name != label {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, migration.Name, label)
+		}
+		if direction == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator applies and reverts the embedded migrations of a database. Only one
+// migrator runs at a time across processes sharing the database.
+type Migrator struct {
+	db         *gorm.DB
+	dialect    string
+	migrations []Migration
+}
+
+// NewMigrator creates a migrator for the database's dialect
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	dialect := db.Dialector.Name()
+	if _, ok := migrationTables[dialect]; !ok {
+		return nil, fmt.Errorf("unsupported database dialect %q", dialect)
+	}
+	migrations, err := LoadMigrations(dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+// Up applies every pending migration in order and returns the ones applied,
+// which on failure excludes those rolled back
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.locked(ctx, func(conn *gorm.DB) error {
+		done, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			err := m.run(conn, migration.Up,
+				"INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+				migration.Version, migration.Name, time.Now().UTC())
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	if err != nil && m.rollsBackAll() {
+		return nil, err
+	}
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns
+// them, which on failure excludes those rolled back
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.locked(ctx, func(conn *gorm.DB) error {
+		done, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+			err := m.run(conn, migration.Down, "DELETE FROM schema_migrations WHERE version = ?", migration.Version)
+			if err != nil {
+				return fmt.Errorf("reverting migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	if err != nil && m.rollsBackAll() {
+		return nil, err
+	}
+	return reverted, err
+}
+
+// rollsBackAll reports whether a failure undoes every migration of the run,
+// as on SQLite where they share the transaction holding the lock
+func (m *Migrator) rollsBackAll() bool {
+	return m.dialect == sqlite.DriverName
+}
+
+// Status lists every known migration and when it was applied
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn := m.db.WithContext(ctx)
+	done := map[int]appliedMigration{}
+	if conn.Migrator().HasTable("schema_migrations") {
+		var err error
+		if done, err = m.applied(conn); err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]MigrationStatus, len(m.migrations))
+	for i, migration := range m.migrations {
+		statuses[i].Migration = migration
+		if row, ok := done[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			statuses[i].AppliedAt = &appliedAt
+		}
+	}
+	return statuses, nil
+}
+
+// applied returns the rows of schema_migrations by version, creating the table if needed
+func (m *Migrator) applied(conn *gorm.DB) (map[int]appliedMigration, error) {
+	if err := conn.Exec(migrationTables[m.dialect]).Error; err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	var rows []appliedMigration
+	if err := conn.Table("schema_migrations").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	done := make(map[int]appliedMigration, len(rows))
+	for _, row := range rows {
+		done[row.Version] = row
+	}
+	return done, nil
+}
+
+// run executes a migration script and records it in schema_migrations atomically
+func (m *Migrator) run(conn *gorm.DB, script, record string, args ...interface{}) error {
+	if m.dialect == sqlite.DriverName {
+		// Already inside the transaction holding the lock
+		if err := execSQLite(conn, script); err != nil {
+			return err
+		}
+		return conn.Exec(record, args...).Error
+	}
+	return conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(script).Error; err != nil {
+			return err
+		}
+		return tx.Exec(record, args...).Error
+	})
+}
+
+// execSQLite executes a script one statement at a time, emulating
+// ADD COLUMN IF NOT EXISTS by skipping the statement when the column exists.
+// Statements are separated by semicolons at the end of a line.
+func execSQLite(conn *gorm.DB, script string) error {
+	for _, statement := range strings.Split(script, ";\n") {
+		statement = stripSQLComments(statement)
+		if strings.TrimSpace(statement) == "" {
+			continue
+		}
+
+		if match := addColumnIfNotExists.FindStringSubmatchIndex(statement); match != nil {
+			table, column := statement[match[2]:match[3]], statement[match[4]:match[5]]
+			if conn.Migrator().HasColumn(table, column) {
+				continue
+			}
+			statement = "ALTER TABLE " + table + " ADD COLUMN " + statement[match[4]:]
+		}
+
+		if err := conn.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stripSQLComments removes the -- comment lines of a statement
+func stripSQLComments(statement string) string {
+	var lines []string
+	for _, line := range strings.Split(statement, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// locked runs fn on a single connection while holding the migration lock:
+// an advisory lock on Postgres, and an immediate transaction, which takes the
+// database's write lock, on SQLite
+func (m *Migrator) locked(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if m.dialect == sqlite.DriverName {
+			if err := conn.Exec("BEGIN IMMEDIATE").Error; err != nil {
+				return fmt.Errorf("failed to acquire migration lock: %w", err)
+			}
+			if err := fn(conn); err != nil {
+				m.release(conn, "ROLLBACK")
+				return err
+			}
+			return conn.Exec("COMMIT").Error
+		}
+
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", migrationLockID).Error; err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		// Unlock even when ctx is done, or the pooled connection would keep the lock
+		defer m.release(conn, "SELECT pg_advisory_unlock($1)", migrationLockID)
+		return fn(conn)
+	})
+}
+
+// release runs the statement ending a locked run directly on the underlying
+// connection: after a failed migration conn keeps the error and would skip it
+func (m *Migrator) release(conn *gorm.DB, statement string, args ...interface{}) {
+	if _, err := conn.Statement.ConnPool.ExecContext(context.Background(), statement, args...); err != nil {
+		log.Printf("Warning: failed to release the migration lock: %v", err)
+	}
+}
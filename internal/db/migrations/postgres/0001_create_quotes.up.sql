@@ -0,0 +1,47 @@
+CREATE TABLE IF NOT EXISTS quotes (
+    id UUID PRIMARY KEY,
+    tag VARCHAR(50) NOT NULL,
+    tag_source VARCHAR(20) NOT NULL,
+    quote_text TEXT NOT NULL,
+    author VARCHAR(255),
+    language VARCHAR(10),
+    explanation TEXT,
+    source VARCHAR(50) NOT NULL,
+    model VARCHAR(100),
+    prompt_version VARCHAR(50),
+    prompt_tokens BIGINT,
+    completion_tokens BIGINT,
+    total_tokens BIGINT,
+    cost_usd DECIMAL,
+    tone VARCHAR(20),
+    length VARCHAR(20),
+    audience VARCHAR(20),
+    text_hash VARCHAR(64),
+    duplicate_of UUID,
+    created_at TIMESTAMPTZ,
+    latency_ms BIGINT,
+    client_ip VARCHAR(45),
+    user_agent TEXT
+);
+
+-- Tables created by earlier releases through GORM's AutoMigrate lack the newer columns
+ALTER TABLE quotes
+    ADD COLUMN IF NOT EXISTS language VARCHAR(10),
+    ADD COLUMN IF NOT EXISTS explanation TEXT,
+    ADD COLUMN IF NOT EXISTS model VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS prompt_version VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS prompt_tokens BIGINT,
+    ADD COLUMN IF NOT EXISTS completion_tokens BIGINT,
+    ADD COLUMN IF NOT EXISTS total_tokens BIGINT,
+    ADD COLUMN IF NOT EXISTS cost_usd DECIMAL,
+    ADD COLUMN IF NOT EXISTS tone VARCHAR(20),
+    ADD COLUMN IF NOT EXISTS length VARCHAR(20),
+    ADD COLUMN IF NOT EXISTS audience VARCHAR(20),
+    ADD COLUMN IF NOT EXISTS text_hash VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS duplicate_of UUID;
+
+CREATE INDEX IF NOT EXISTS idx_quotes_tag ON quotes (tag);
+CREATE INDEX IF NOT EXISTS idx_quotes_model ON quotes (model);
+CREATE INDEX IF NOT EXISTS idx_quotes_prompt_version ON quotes (prompt_version);
+CREATE INDEX IF NOT EXISTS idx_quotes_text_hash ON quotes (text_hash);
+CREATE INDEX IF NOT EXISTS idx_quotes_duplicate_of ON quotes (duplicate_of);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS budget_usages (
+    id BIGSERIAL PRIMARY KEY,
+    key VARCHAR(150) NOT NULL,
+    period VARCHAR(10) NOT NULL,
+    period_start TIMESTAMPTZ NOT NULL,
+    tokens BIGINT NOT NULL DEFAULT 0,
+    cost_usd DECIMAL NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_budget_usage_period ON budget_usages (key, period, period_start);
@@ -0,0 +1,3 @@
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_quotes_deleted_at ON quotes (deleted_at);
@@ -0,0 +1,49 @@
+-- UUIDs are stored in their text form
+CREATE TABLE IF NOT EXISTS quotes (
+    id TEXT PRIMARY KEY,
+    tag TEXT NOT NULL,
+    tag_source TEXT NOT NULL,
+    quote_text TEXT NOT NULL,
+    author TEXT,
+    language TEXT,
+    explanation TEXT,
+    source TEXT NOT NULL,
+    model TEXT,
+    prompt_version TEXT,
+    prompt_tokens INTEGER,
+    completion_tokens INTEGER,
+    total_tokens INTEGER,
+    cost_usd REAL,
+    tone TEXT,
+    length TEXT,
+    audience TEXT,
+    text_hash TEXT,
+    duplicate_of TEXT,
+    created_at DATETIME,
+    latency_ms INTEGER,
+    client_ip TEXT,
+    user_agent TEXT
+);
+
+-- Tables created by earlier releases through GORM's AutoMigrate lack the newer
+-- columns. SQLite has no ADD COLUMN IF NOT EXISTS: the migrator skips these
+-- statements for columns that already exist.
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS language TEXT;
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS explanation TEXT;
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS model TEXT;
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS prompt_version TEXT;
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS prompt_tokens INTEGER;
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS completion_tokens INTEGER;
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS total_tokens INTEGER;
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS cost_usd REAL;
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS tone TEXT;
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS length TEXT;
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS audience TEXT;
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS text_hash TEXT;
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS duplicate_of TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_quotes_tag ON quotes (tag);
+CREATE INDEX IF NOT EXISTS idx_quotes_model ON quotes (model);
+CREATE INDEX IF NOT EXISTS idx_quotes_prompt_version ON quotes (prompt_version);
+CREATE INDEX IF NOT EXISTS idx_quotes_text_hash ON quotes (text_hash);
+CREATE INDEX IF NOT EXISTS idx_quotes_duplicate_of ON quotes (duplicate_of);
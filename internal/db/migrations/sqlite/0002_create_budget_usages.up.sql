@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS budget_usages (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    key TEXT NOT NULL,
+    period TEXT NOT NULL,
+    period_start DATETIME NOT NULL,
+    tokens INTEGER NOT NULL DEFAULT 0,
+    cost_usd REAL NOT NULL DEFAULT 0,
+    updated_at DATETIME
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_budget_usage_period ON budget_usages (key, period, period_start);
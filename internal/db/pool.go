@@ -0,0 +1,114 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Backoff between connection attempts, doubling from the initial delay
+const (
+	connectInitialBackoff = 500 * time.Millisecond
+	connectMaxBackoff     = 5 * time.Second
+)
+
+// PoolConfig holds connection pool and startup retry settings
+type PoolConfig struct {
+	MaxOpenConns int
+	MaxIdleConns int
+	// ConnMaxLifetime and ConnMaxIdleTime close older or idle connections; 0 keeps them
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// ConnectTimeout bounds the retries while the database is not ready; 0 tries once
+	ConnectTimeout time.Duration
+}
+
+// PoolConfigFromEnv reads DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS, DB_CONN_MAX_LIFETIME,
+// DB_CONN_MAX_IDLE_TIME and DB_CONNECT_TIMEOUT
+func PoolConfigFromEnv() PoolConfig {
+	config := PoolConfig{
+		MaxOpenConns:    100,
+		MaxIdleConns:    10,
+		ConnMaxLifetime: time.Hour,
+		ConnectTimeout:  30 * time.Second,
+	}
+
+	readInt := func(key string, target *int, min int) {
+		value := os.Getenv(key)
+		if value == "" {
+			return
+		}
+		if parsed, err := strconv.Atoi(value); err == nil && parsed >= min {
+			*target = parsed
+		} else {
+			log.Printf("Warning: invalid %s %q, using %d", key, value, *target)
+		}
+	}
+	readDuration := func(key string, target *time.Duration) {
+		value := os.Getenv(key)
+		if value == "" {
+			return
+		}
+		if parsed, err := time.ParseDuration(value); err == nil && parsed >= 0 {
+			*target = parsed
+		} else {
+			log.Printf("Warning: invalid %s %q, using %s", key, value, *target)
+		}
+	}
+
+	readInt("DB_MAX_OPEN_CONNS", &config.MaxOpenConns, 1)
+	readInt("DB_MAX_IDLE_CONNS", &config.MaxIdleConns, 0)
+	readDuration("DB_CONN_MAX_LIFETIME", &config.ConnMaxLifetime)
+	readDuration("DB_CONN_MAX_IDLE_TIME", &config.ConnMaxIdleTime)
+	readDuration("DB_CONNECT_TIMEOUT", &config.ConnectTimeout)
+
+	return config
+}
+
+// Apply configures the pool of sqlDB
+func (c PoolConfig) Apply(sqlDB *sql.DB) {
+	sqlDB.SetMaxOpenConns(c.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(c.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(c.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(c.ConnMaxIdleTime)
+}
+
+// openWithRetry opens the database, retrying with exponential backoff until it
+// answers or the timeout passes
+func openWithRetry(dsn string, config *gorm.Config, timeout time.Duration) (*gorm.DB, error) {
+	deadline := time.Now().Add(timeout)
+	delay := connectInitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		db, err := gorm.Open(dialector(dsn), config)
+		if err == nil {
+			return db, nil
+		}
+		// A failed ping leaves the pool open
+		if db != nil {
+			if sqlDB, dbErr := db.DB(); dbErr == nil {
+				sqlDB.Close()
+			}
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, fmt.Errorf("failed to connect to database after %d attempts: %w", attempt, err)
+		}
+		if delay > remaining {
+			delay = remaining
+		}
+		log.Printf("Database not ready (attempt %d): %v; retrying in %s", attempt, err, delay)
+		time.Sleep(delay)
+
+		delay *= 2
+		if delay > connectMaxBackoff {
+			delay = connectMaxBackoff
+		}
+	}
+}
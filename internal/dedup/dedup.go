@@ -0,0 +1,173 @@
+// Package dedup detects generated quotes that repeat or closely paraphrase
+// quotes already stored for the same tag.
+package dedup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Modes accepted by QUOTE_DEDUP_MODE
+const (
+	// ModeOff stores every quote as is
+	ModeOff = "off"
+	// ModeRegenerate asks for another quote, storing a reference if it still repeats
+	ModeRegenerate = "regenerate"
+	// ModeReuse serves the existing quote instead of storing a copy
+	ModeReuse = "reuse"
+	// ModeReference stores the quote with a duplicate_of reference to the original
+	ModeReference = "duplicate_of"
+)
+
+// Config holds duplicate detection settings
+type Config struct {
+	Mode string
+	// Threshold is the trigram Jaccard similarity from which quotes are near-duplicates
+	Threshold float64
+	// Window is the number of recent quotes of the tag compared for similarity
+	Window int
+	// MaxAttempts bounds the regenerations in ModeRegenerate
+	MaxAttempts int
+}
+
+// ConfigFromEnv reads QUOTE_DEDUP_MODE, QUOTE_DEDUP_THRESHOLD, QUOTE_DEDUP_WINDOW
+// and QUOTE_DEDUP_ATTEMPTS
+func ConfigFromEnv() Config {
+	config := Config{
+		Mode:        ModeReference,
+		Threshold:   0.8,
+		Window:      50,
+		MaxAttempts: 2,
+	}
+
+	if value := os.Getenv("QUOTE_DEDUP_MODE"); value != "" {
+		switch mode := strings.ToLower(value); mode {
+		case ModeOff, ModeRegenerate, ModeReuse, ModeReference:
+			config.Mode = mode
+		default:
+			log.Printf("Warning: invalid QUOTE_DEDUP_MODE %q, using %q", value, config.Mode)
+		}
+	}
+	if value := os.Getenv("QUOTE_DEDUP_THRESHOLD"); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil && parsed > 0 && parsed <= 1 {
+			config.Threshold = parsed
+		} else {
+			log.Printf("Warning: invalid QUOTE_DEDUP_THRESHOLD %q, using %g", value, config.Threshold)
+		}
+	}
+	for key, target := range map[string]*int{"QUOTE_DEDUP_WINDOW": &config.Window, "QUOTE_DEDUP_ATTEMPTS": &config.MaxAttempts} {
+		value := os.Getenv(key)
+		if value == "" {
+			continue
+		}
+		if parsed, err := strconv.Atoi(value); err == nil && parsed >= 0 {
+			*target = parsed
+		} else {
+			log.Printf("Warning: invalid %s %q, using %d", key, value, *target)
+		}
+	}
+
+	return config
+}
+
+// Enabled reports whether quotes are checked at all
+func (c Config) Enabled() bool {
+	return c.Mode != ModeOff
+}
+
+// Normalize lowercases the text and reduces it to letters, digits and single spaces,
+// so quotes differing only in punctuation or quotation marks compare equal
+func Normalize(text string) string {
+	var normalized strings.Builder
+	space := false
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && normalized.Len() > 0 {
+				normalized.WriteByte(' ')
+			}
+			space = false
+			normalized.WriteRune(r)
+		case unicode.IsSpace(r):
+			space = true
+		}
+	}
+	return normalized.String()
+}
+
+// Hash returns the hex SHA-256 of the normalised text
+func Hash(text string) string {
+	sum := sha256.Sum256([]byte(Normalize(text)))
+	return hex.EncodeToString(sum[:])
+}
+
+// trigrams returns the set of character trigrams of the normalised text
+func trigrams(text string) map[string]bool {
+	runes := []rune(" " + Normalize(text) + " ")
+	set := make(map[string]bool)
+	for i := 0; i+3 <= len(runes); i++ {
+		set[string(runes[i:i+3])] = true
+	}
+	return set
+}
+
+// Similarity returns the Jaccard similarity of the texts' trigram sets, from 0 to 1
+func Similarity(a, b string) float64 {
+	return jaccard(trigrams(a), trigrams(b))
+}
+
+// Candidate is a stored quote a new quote is compared with
+type Candidate struct {
+	Text string
+	// Hash is the stored Hash of Text; empty for quotes stored before hashing
+	Hash string
+}
+
+// Detector finds the stored quote a new quote duplicates
+type Detector struct {
+	Config Config
+}
+
+// NewDetector creates a detector with the given settings
+func NewDetector(config Config) *Detector {
+	return &Detector{Config: config}
+}
+
+// Find returns the index of a candidate the text repeats exactly, else of the
+// candidate it most resembles at or above the threshold, or -1 if there is none
+func (d *Detector) Find(text string, candidates []Candidate) int {
+	hash := Hash(text)
+	for i, candidate := range candidates {
+		if candidate.Hash == hash {
+			return i
+		}
+	}
+
+	grams := trigrams(text)
+	best, bestScore := -1, 0.0
+	for i, candidate := range candidates {
+		if score := jaccard(grams, trigrams(candidate.Text)); score >= d.Config.Threshold && score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+// jaccard returns the Jaccard similarity of two trigram sets
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	shared := 0
+	for gram := range a {
+		if b[gram] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
@@ -1,7 +1,12 @@
 package metrics
 
 import (
+	"database/sql"
+	"errors"
+	"sync"
+
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
@@ -18,12 +23,121 @@ var (
 		Help: "Number of quotes fetched by tag",
 	}, []string{"tag"})
 
+	// QuotesByModel counts quotes by the provider and model that generated them
+	QuotesByModel = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "quotes_by_model",
+		Help: "Number of quotes generated by provider and model",
+	}, []string{"provider", "model"})
+
+	// QuoteTokensTotal counts the tokens used to generate quotes by model and token type
+	QuoteTokensTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "quote_tokens_total",
+		Help: "Total number of tokens used for quote generation by model and type (prompt or completion)",
+	}, []string{"model", "type"})
+
+	// QuoteCostUSDTotal sums the cost of generating quotes by model
+	QuoteCostUSDTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "quote_cost_usd_total",
+		Help: "Total cost of quote generation in USD by model",
+	}, []string{"model"})
+
+	// QuoteBudgetUsed exposes the global budget usage of the current period
+	QuoteBudgetUsed = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "quote_budget_used",
+		Help: "Global quote generation usage in the current period by period (day or month) and unit (tokens or usd)",
+	}, []string{"period", "unit"})
+
+	// QuoteBudgetLimit exposes the configured global budget limits
+	QuoteBudgetLimit = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "quote_budget_limit",
+		Help: "Configured global quote generation budget by period and unit, 0 when unlimited",
+	}, []string{"period", "unit"})
+
+	// QuoteBudgetExceededTotal counts requests that hit an exhausted budget
+	QuoteBudgetExceededTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "quote_budget_exceeded_total",
+		Help: "Total number of quote requests over budget by scope (global or consumer) and action (fallback or rejected)",
+	}, []string{"scope", "action"})
+
+	// QuoteCacheHitsTotal counts quote requests served without generating a new quote
+	QuoteCacheHitsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "quote_cache_hits_total",
+		Help: "Total number of quote requests served from the cache by source (memory or database)",
+	}, []string{"source"})
+
+	// QuoteCacheMissesTotal counts cacheable quote requests that needed a new quote
+	QuoteCacheMissesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "quote_cache_misses_total",
+		Help: "Total number of cacheable quote requests that had to generate a new quote",
+	})
+
+	// QuoteCoalescedTotal counts requests that joined a generation already in flight
+	QuoteCoalescedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "quote_coalesced_total",
+		Help: "Total number of quote requests that shared an upstream call already in flight",
+	})
+
+	// QuotePoolDepth exposes the number of pre-generated quotes waiting per tag
+	QuotePoolDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "quote_pool_depth",
+		Help: "Number of pre-generated quotes in the pool by tag",
+	}, []string{"tag"})
+
+	// QuotePoolMissesTotal counts requests for a pooled tag that found its pool empty
+	QuotePoolMissesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "quote_pool_misses_total",
+		Help: "Total number of quote requests that found the tag's pool empty",
+	}, []string{"tag"})
+
+	// QuotePoolRefillLatency measures the generations that refill the pool
+	QuotePoolRefillLatency = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "quote_pool_refill_latency_seconds",
+		Help:    "Latency of quote generations refilling the pool in seconds",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	// QuoteDuplicateChecksTotal counts generated quotes checked for duplicates
+	QuoteDuplicateChecksTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "quote_duplicate_checks_total",
+		Help: "Total number of generated quotes checked against stored quotes by tag",
+	}, []string{"tag"})
+
+	// QuoteDuplicatesTotal counts generated quotes found to repeat a stored quote
+	QuoteDuplicatesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "quote_duplicates_total",
+		Help: "Total number of generated quotes that duplicate or nearly duplicate a stored quote by tag",
+	}, []string{"tag"})
+
+	// QuoteDuplicateRate exposes the share of checked quotes that were duplicates
+	QuoteDuplicateRate = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "quote_duplicate_rate",
+		Help: "Share of generated quotes that were duplicates since startup by tag, from 0 to 1",
+	}, []string{"tag"})
+
+	// QuoteModerationBlockedTotal counts tags and quotes blocked by the content filter
+	QuoteModerationBlockedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "quote_moderation_blocked_total",
+		Help: "Total number of custom tags (input) and generated quotes (output) blocked by moderation, by category",
+	}, []string{"target", "category"})
+
 	// QuoteFetchErrorsTotal counts the total number of errors fetching quotes
 	QuoteFetchErrorsTotal = promauto.NewCounter(prometheus.CounterOpts{
 		Name: "quote_fetch_errors_total",
 		Help: "Total number of errors while fetching quotes",
 	})
 
+	// QuoteGenerationsCancelledTotal counts generations abandoned because the client went away
+	QuoteGenerationsCancelledTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "quote_generations_cancelled_total",
+		Help: "Total number of quote generations cancelled by the client",
+	})
+
+	// QuoteRejectionsTotal counts generated quotes rejected by the post-processing pipeline
+	QuoteRejectionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "quote_rejections_total",
+		Help: "Total number of generated quotes rejected by validation, by provider and reason",
+	}, []string{"provider", "reason"})
+
 	// QuoteFetchLatency measures the latency of quote fetch operations
 	QuoteFetchLatency = promauto.NewHistogram(prometheus.HistogramOpts{
 		Name:    "quote_fetch_latency_seconds",
@@ -37,17 +151,31 @@ var (
 		Help: "Total number of HTTP requests",
 	}, []string{"method", "route", "status"})
 
-	// OpenRouterUp indicates if OpenRouter API is up (1) or down (0)
-	OpenRouterUp = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "openrouter_up",
-		Help: "Indicates if the last OpenRouter API call succeeded (1) or failed (0)",
-	})
+	// QuoteProviderUp indicates if each quote provider is up (1) or down (0)
+	QuoteProviderUp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "quote_provider_up",
+		Help: "Indicates if the last call to a quote provider succeeded (1) or failed (0)",
+	}, []string{"provider"})
+
+	// CircuitBreakerState exposes the circuit breaker state of each provider
+	CircuitBreakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "circuit_breaker_state",
+		Help: "Circuit breaker state per quote provider: 0 closed, 1 open, 2 half-open",
+	}, []string{"provider"})
 )
 
+// knownProviders holds the providers whose status has been initialized
+var knownProviders sync.Map
+
 // Init initializes metrics (called at startup)
 func Init() {
-	// Set initial OpenRouter status to unknown (0)
-	OpenRouterUp.Set(0)
+	// Drop provider statuses left over from a previous configuration
+	QuoteProviderUp.Reset()
+	CircuitBreakerState.Reset()
+	knownProviders.Range(func(provider, _ any) bool {
+		knownProviders.Delete(provider)
+		return true
+	})
 }
 
 // RecordQuoteFetched increments the quotes fetched counter
@@ -56,11 +184,111 @@ func RecordQuoteFetched(tag string) {
 	QuotesByTag.WithLabelValues(tag).Inc()
 }
 
+// RecordQuoteModel increments the quotes counter of the model that generated a quote
+func RecordQuoteModel(provider, model string) {
+	QuotesByModel.WithLabelValues(provider, model).Inc()
+}
+
+// RecordUsage adds the tokens and cost of a generation to the usage counters
+func RecordUsage(model string, promptTokens, completionTokens int, costUSD float64) {
+	QuoteTokensTotal.WithLabelValues(model, "prompt").Add(float64(promptTokens))
+	QuoteTokensTotal.WithLabelValues(model, "completion").Add(float64(completionTokens))
+	QuoteCostUSDTotal.WithLabelValues(model).Add(costUSD)
+}
+
+// SetBudgetUsage sets the global budget usage of a period
+func SetBudgetUsage(period string, tokens, costUSD float64) {
+	QuoteBudgetUsed.WithLabelValues(period, "tokens").Set(tokens)
+	QuoteBudgetUsed.WithLabelValues(period, "usd").Set(costUSD)
+}
+
+// SetBudgetLimit sets the global budget limits of a period
+func SetBudgetLimit(period string, tokens, costUSD float64) {
+	QuoteBudgetLimit.WithLabelValues(period, "tokens").Set(tokens)
+	QuoteBudgetLimit.WithLabelValues(period, "usd").Set(costUSD)
+}
+
+// RecordBudgetExceeded increments the over-budget requests counter
+func RecordBudgetExceeded(scope, action string) {
+	QuoteBudgetExceededTotal.WithLabelValues(scope, action).Inc()
+}
+
+// RecordCacheHit increments the cache hits counter of a source
+func RecordCacheHit(source string) {
+	QuoteCacheHitsTotal.WithLabelValues(source).Inc()
+}
+
+// RecordCacheMiss increments the cache misses counter
+func RecordCacheMiss() {
+	QuoteCacheMissesTotal.Inc()
+}
+
+// RecordQuoteCoalesced increments the coalesced requests counter
+func RecordQuoteCoalesced() {
+	QuoteCoalescedTotal.Inc()
+}
+
+// SetPoolDepth sets the number of pooled quotes for a tag
+func SetPoolDepth(tag string, depth int) {
+	QuotePoolDepth.WithLabelValues(tag).Set(float64(depth))
+}
+
+// RecordPoolMiss increments the pool misses counter of a tag
+func RecordPoolMiss(tag string) {
+	QuotePoolMissesTotal.WithLabelValues(tag).Inc()
+}
+
+// RecordPoolRefill records the latency of a pool refill generation
+func RecordPoolRefill(seconds float64) {
+	QuotePoolRefillLatency.Observe(seconds)
+}
+
+// duplicateCounts tracks the checks behind QuoteDuplicateRate
+var duplicateCounts = struct {
+	sync.Mutex
+	checks     map[string]float64
+	duplicates map[string]float64
+}{
+	checks:     make(map[string]float64),
+	duplicates: make(map[string]float64),
+}
+
+// RecordDuplicateCheck counts a duplicate check of a tag and updates its duplicate rate
+func RecordDuplicateCheck(tag string, duplicate bool) {
+	QuoteDuplicateChecksTotal.WithLabelValues(tag).Inc()
+	if duplicate {
+		QuoteDuplicatesTotal.WithLabelValues(tag).Inc()
+	}
+
+	duplicateCounts.Lock()
+	defer duplicateCounts.Unlock()
+	duplicateCounts.checks[tag]++
+	if duplicate {
+		duplicateCounts.duplicates[tag]++
+	}
+	QuoteDuplicateRate.WithLabelValues(tag).Set(duplicateCounts.duplicates[tag] / duplicateCounts.checks[tag])
+}
+
+// RecordModerationBlocked increments the blocked content counter
+func RecordModerationBlocked(target, category string) {
+	QuoteModerationBlockedTotal.WithLabelValues(target, category).Inc()
+}
+
 // RecordQuoteError increments the error counter
 func RecordQuoteError() {
 	QuoteFetchErrorsTotal.Inc()
 }
 
+// RecordQuoteCancelled increments the cancelled generations counter
+func RecordQuoteCancelled() {
+	QuoteGenerationsCancelledTotal.Inc()
+}
+
+// RecordQuoteRejected increments the rejected quotes counter
+func RecordQuoteRejected(provider, reason string) {
+	QuoteRejectionsTotal.WithLabelValues(provider, reason).Inc()
+}
+
 // RecordLatency records the latency of a quote fetch
 func RecordLatency(seconds float64) {
 	QuoteFetchLatency.Observe(seconds)
@@ -71,11 +299,39 @@ func RecordHTTPRequest(method, route, status string) {
 	HTTPRequestsTotal.WithLabelValues(method, route, status).Inc()
 }
 
-// SetOpenRouterStatus sets the OpenRouter status
-func SetOpenRouterStatus(up bool) {
+// SetProviderStatus sets the status of a quote provider
+func SetProviderStatus(provider string, up bool) {
 	if up {
-		OpenRouterUp.Set(1)
+		QuoteProviderUp.WithLabelValues(provider).Set(1)
 	} else {
-		OpenRouterUp.Set(0)
+		QuoteProviderUp.WithLabelValues(provider).Set(0)
+	}
+}
+
+// InitProviderStatus reports a provider as down until its first call. Providers
+// shared by several chains keep the status already reported for them.
+func InitProviderStatus(provider string) {
+	if _, known := knownProviders.LoadOrStore(provider, true); !known {
+		SetProviderStatus(provider, false)
+	}
+}
+
+// SetCircuitBreakerState sets the circuit breaker state of a quote provider
+func SetCircuitBreakerState(provider string, state int) {
+	CircuitBreakerState.WithLabelValues(provider).Set(float64(state))
+}
+
+// RegisterDBStats exports the connection pool statistics of db, such as open and
+// in-use connections and waits, as go_sql_* metrics labelled with name. A pool
+// registered earlier under the same name is replaced.
+func RegisterDBStats(db *sql.DB, name string) {
+	collector := collectors.NewDBStatsCollector(db, name)
+	if err := prometheus.Register(collector); err != nil {
+		var registered prometheus.AlreadyRegisteredError
+		if !errors.As(err, &registered) {
+			panic(err)
+		}
+		prometheus.Unregister(registered.ExistingCollector)
+		prometheus.MustRegister(collector)
 	}
 }
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// BudgetUsage is the spending of one budget key during one period
+type BudgetUsage struct {
+	ID          uint      `gorm:"primaryKey" json:"-"`
+	Key         string    `gorm:"type:varchar(150);not null;uniqueIndex:idx_budget_usage_period" json:"key"` // "global" or "consumer:<id>"
+	Period      string    `gorm:"type:varchar(10);not null;uniqueIndex:idx_budget_usage_period" json:"period"`
+	PeriodStart time.Time `gorm:"not null;uniqueIndex:idx_budget_usage_period" json:"period_start"`
+	Tokens      int64     `gorm:"not null;default:0" json:"tokens"`
+	CostUSD     float64   `gorm:"not null;default:0" json:"cost_usd"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
@@ -0,0 +1,42 @@
+package models
+
+// ValidTones is the list of tones a quote can be requested in
+var ValidTones = []string{"funny", "stoic", "poetic", "uplifting"}
+
+// ValidLengths is the list of quote lengths that can be requested
+var ValidLengths = []string{"one_liner", "two_sentences"}
+
+// ValidAudiences is the list of audiences a quote can be written for
+var ValidAudiences = []string{"kids", "teens", "professional"}
+
+// Languages maps the supported output language codes to their English names
+var Languages = map[string]string{
+	"ar": "Arabic",
+	"de": "German",
+	"en": "English",
+	"es": "Spanish",
+	"fr": "French",
+	"hi": "Hindi",
+	"it": "Italian",
+	"ja": "Japanese",
+	"nl": "Dutch",
+	"pt": "Portuguese",
+	"ur": "Urdu",
+	"zh": "Chinese",
+}
+
+// IsValidOption checks if a value is in the given allow-list
+func IsValidOption(value string, allowed []string) bool {
+	for _, option := range allowed {
+		if value == option {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidLanguage checks if a language code is supported
+func IsValidLanguage(code string) bool {
+	_, ok := Languages[code]
+	return ok
+}
@@ -7,18 +7,33 @@ import (
 	"gorm.io/gorm"
 )
 
-// Quote represents a generated quote stored in the database
+// Quote represents a generated quote stored in the database. The uuid columns
+// are native in Postgres and hold the text form of the UUID in SQLite.
 type Quote struct {
-	ID         uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
-	Tag        string    `gorm:"type:varchar(50);not null;index" json:"tag"`
-	TagSource  string    `gorm:"type:varchar(20);not null" json:"tag_source"` // "preset" or "custom"
-	QuoteText  string    `gorm:"type:text;not null" json:"quote_text"`
-	Author     *string   `gorm:"type:varchar(255)" json:"author,omitempty"`
-	Source     string    `gorm:"type:varchar(50);not null" json:"source"` // "openrouter"
-	CreatedAt  time.Time `json:"created_at"`
-	LatencyMs  int       `json:"latency_ms"`
-	ClientIP   string    `gorm:"type:varchar(45)" json:"client_ip"`
-	UserAgent  string    `gorm:"type:text" json:"user_agent"`
+	ID               uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	Tag              string         `gorm:"type:varchar(50);not null;index" json:"tag"`
+	TagSource        string         `gorm:"type:varchar(20);not null" json:"tag_source"` // "preset" or "custom"
+	QuoteText        string         `gorm:"type:text;not null" json:"quote_text"`
+	Author           *string        `gorm:"type:varchar(255)" json:"author,omitempty"`
+	Language         *string        `gorm:"type:varchar(10)" json:"language,omitempty"`
+	Explanation      *string        `gorm:"type:text" json:"explanation,omitempty"`
+	Source           string         `gorm:"type:varchar(50);not null" json:"source"`                // provider, e.g. "openrouter" or "corpus"
+	Model            string         `gorm:"type:varchar(100);index" json:"model,omitempty"`         // model reported by the provider
+	PromptVersion    string         `gorm:"type:varchar(50);index" json:"prompt_version,omitempty"` // prompt template, empty for corpus quotes
+	PromptTokens     int            `json:"prompt_tokens"`
+	CompletionTokens int            `json:"completion_tokens"`
+	TotalTokens      int            `json:"total_tokens"`
+	CostUSD          float64        `json:"cost_usd"`
+	Tone             string         `gorm:"type:varchar(20)" json:"tone,omitempty"`
+	Length           string         `gorm:"type:varchar(20)" json:"length,omitempty"`
+	Audience         string         `gorm:"type:varchar(20)" json:"audience,omitempty"`
+	TextHash         string         `gorm:"type:varchar(64);index" json:"text_hash,omitempty"` // hash of the normalised quote text
+	DuplicateOf      *uuid.UUID     `gorm:"type:uuid;index" json:"duplicate_of,omitempty"`     // earlier quote this one repeats
+	CreatedAt        time.Time      `json:"created_at"`
+	LatencyMs        int            `json:"latency_ms"`
+	ClientIP         string         `gorm:"type:varchar(45)" json:"client_ip"`
+	UserAgent        string         `gorm:"type:text" json:"user_agent"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"` // set by soft deletes, which hide the quote from queries
 }
 
 // BeforeCreate will set a UUID rather than numeric ID
@@ -0,0 +1,123 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// apiRule names violations reported by a Moderator
+const apiRule = "moderation_api"
+
+// Moderator screens text with an external moderation service
+type Moderator interface {
+	// Moderate returns a *Violation when the service flags the text
+	Moderate(ctx context.Context, text string) (*Violation, error)
+}
+
+// APIModerator calls an OpenAI-compatible /moderations endpoint
+type APIModerator struct {
+	BaseURL    string
+	APIKey     string
+	Model      string
+	HTTPClient *http.Client
+}
+
+// NewAPIModeratorFromEnv returns the moderator configured by QUOTE_MODERATION_API_URL,
+// QUOTE_MODERATION_API_KEY and QUOTE_MODERATION_MODEL, or nil when no URL is set
+func NewAPIModeratorFromEnv() *APIModerator {
+	baseURL := os.Getenv("QUOTE_MODERATION_API_URL")
+	if baseURL == "" {
+		return nil
+	}
+
+	model := os.Getenv("QUOTE_MODERATION_MODEL")
+	if model == "" {
+		model = "omni-moderation-latest"
+	}
+
+	return &APIModerator{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		APIKey:  os.Getenv("QUOTE_MODERATION_API_KEY"),
+		Model:   model,
+		HTTPClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// moderationRequest is the body of a moderation call
+type moderationRequest struct {
+	Model string `json:"model,omitempty"`
+	Input string `json:"input"`
+}
+
+// moderationResponse is the response of a moderation call
+type moderationResponse struct {
+	Results []struct {
+		Flagged    bool            `json:"flagged"`
+		Categories map[string]bool `json:"categories"`
+	} `json:"results"`
+}
+
+// Moderate asks the moderation service whether the text is acceptable
+func (m *APIModerator) Moderate(ctx context.Context, text string) (*Violation, error) {
+	body, err := json.Marshal(moderationRequest{Model: m.Model, Input: text})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal moderation request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.BaseURL+"/moderations", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create moderation request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if m.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+m.APIKey)
+	}
+
+	resp, err := m.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("moderation request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read moderation response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("moderation API returned HTTP %d: %s", resp.StatusCode, string(data))
+	}
+
+	var response moderationResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal moderation response: %w", err)
+	}
+
+	for _, result := range response.Results {
+		if !result.Flagged {
+			continue
+		}
+		var categories []string
+		for category, flagged := range result.Categories {
+			if flagged {
+				categories = append(categories, category)
+			}
+		}
+		sort.Strings(categories)
+		category := "flagged"
+		if len(categories) > 0 {
+			category = categories[0]
+		}
+		return &Violation{Target: TargetOutput, Rule: apiRule, Category: category}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,195 @@
+// Package moderation screens custom tags and generated quotes against a
+// configurable rule set and, optionally, a provider moderation API.
+package moderation
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed rules.yaml
+var defaultRules []byte
+
+// Targets a rule applies to, also used as the quote_moderation_blocked_total label
+const (
+	TargetInput  = "input"
+	TargetOutput = "output"
+)
+
+// Rule matches text by regular expressions and/or whole words
+type Rule struct {
+	Name     string   `yaml:"name"`
+	Category string   `yaml:"category"`
+	Targets  []string `yaml:"targets"`
+	Patterns []string `yaml:"patterns"`
+	Words    []string `yaml:"words"`
+}
+
+// ruleSet is the file format of a rule set
+type ruleSet struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// compiledRule is a rule with its expressions compiled
+type compiledRule struct {
+	Rule
+	expressions []*regexp.Regexp
+}
+
+// appliesTo reports whether the rule screens the target
+func (r *compiledRule) appliesTo(target string) bool {
+	for _, t := range r.Targets {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
+
+// Violation describes text blocked by moderation
+type Violation struct {
+	Target   string
+	Rule     string
+	Category string
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("%s blocked by moderation rule %s (%s)", v.Target, v.Rule, v.Category)
+}
+
+// Filter screens text against a rule set and an optional Moderator
+type Filter struct {
+	rules []compiledRule
+	// Moderator, when set, additionally screens generated quotes
+	Moderator Moderator
+}
+
+var (
+	embeddedFilter     *Filter
+	embeddedFilterOnce sync.Once
+)
+
+// EmbeddedRules returns a filter with the rule set bundled with the binary
+func EmbeddedRules() *Filter {
+	embeddedFilterOnce.Do(func() {
+		var err error
+		embeddedFilter, err = ParseRules(defaultRules)
+		if err != nil {
+			panic(fmt.Sprintf("invalid embedded moderation rules: %v", err))
+		}
+	})
+	return embeddedFilter
+}
+
+// NewFilter loads the rules in QUOTE_MODERATION_RULES, or the bundled ones,
+// and the moderation API configured by QUOTE_MODERATION_API_URL. It returns
+// nil when QUOTE_MODERATION is "off".
+func NewFilter() (*Filter, error) {
+	if strings.EqualFold(os.Getenv("QUOTE_MODERATION"), "off") {
+		return nil, nil
+	}
+
+	filter := EmbeddedRules()
+	if path := os.Getenv("QUOTE_MODERATION_RULES"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read moderation rules: %w", err)
+		}
+		if filter, err = ParseRules(data); err != nil {
+			return nil, fmt.Errorf("invalid moderation rules %s: %w", path, err)
+		}
+		log.Printf("Loaded %d moderation rules from %s", len(filter.rules), path)
+	}
+
+	if moderator := NewAPIModeratorFromEnv(); moderator != nil {
+		withAPI := *filter
+		withAPI.Moderator = moderator
+		filter = &withAPI
+	}
+	return filter, nil
+}
+
+// ParseRules decodes and compiles a YAML rule set
+func ParseRules(data []byte) (*Filter, error) {
+	var set ruleSet
+	if err := yaml.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	filter := &Filter{}
+	for _, rule := range set.Rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("moderation rule without a name")
+		}
+		for _, target := range rule.Targets {
+			if target != TargetInput && target != TargetOutput {
+				return nil, fmt.Errorf("moderation rule %s has unknown target %q", rule.Name, target)
+			}
+		}
+		if rule.Category == "" {
+			rule.Category = rule.Name
+		}
+
+		compiled := compiledRule{Rule: rule}
+		patterns := append([]string{}, rule.Patterns...)
+		if len(rule.Words) > 0 {
+			words := make([]string, len(rule.Words))
+			for i, word := range rule.Words {
+				words[i] = regexp.QuoteMeta(word)
+			}
+			patterns = append(patterns, `\b(`+strings.Join(words, "|")+`)\b`)
+		}
+		for _, pattern := range patterns {
+			expression, err := regexp.Compile("(?i)" + pattern)
+			if err != nil {
+				return nil, fmt.Errorf("moderation rule %s: %w", rule.Name, err)
+			}
+			compiled.expressions = append(compiled.expressions, expression)
+		}
+		filter.rules = append(filter.rules, compiled)
+	}
+	return filter, nil
+}
+
+// Match returns the first rule for the target that matches the text, or nil
+func (f *Filter) Match(target, text string) *Violation {
+	// Collapse whitespace so line breaks and padding cannot split a phrase
+	normalized := strings.Join(strings.Fields(text), " ")
+	for i := range f.rules {
+		rule := &f.rules[i]
+		if !rule.appliesTo(target) {
+			continue
+		}
+		for _, expression := range rule.expressions {
+			if expression.MatchString(normalized) {
+				return &Violation{Target: target, Rule: rule.Name, Category: rule.Category}
+			}
+		}
+	}
+	return nil
+}
+
+// CheckInput screens a custom tag against the input rules
+func (f *Filter) CheckInput(tag string) *Violation {
+	return f.Match(TargetInput, tag)
+}
+
+// CheckOutput screens a generated quote against the output rules and the
+// Moderator. A failed moderation call is returned as an error.
+func (f *Filter) CheckOutput(ctx context.Context, text string) (*Violation, error) {
+	if violation := f.Match(TargetOutput, text); violation != nil {
+		return violation, nil
+	}
+	if f.Moderator == nil {
+		return nil, nil
+	}
+	return f.Moderator.Moderate(ctx, text)
+}
@@ -0,0 +1,361 @@
+// Package pool keeps pre-generated quotes for the preset tags so requests
+// without options can be answered without waiting for a provider.
+package pool
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Adeel56/quotebox/internal/budget"
+	"github.com/Adeel56/quotebox/internal/client"
+	"github.com/Adeel56/quotebox/internal/metrics"
+	"github.com/Adeel56/quotebox/internal/models"
+)
+
+// Config holds pool settings
+type Config struct {
+	// Size is the number of quotes kept per tag; 0 disables the pool
+	Size int
+	// Workers bounds the number of concurrent refill generations
+	Workers int
+	// Rate bounds refill generations per second across all workers
+	Rate float64
+	// MaxAge discards pooled quotes older than this
+	MaxAge time.Duration
+	// Timeout bounds each refill generation
+	Timeout time.Duration
+	Tags    []string
+}
+
+// ConfigFromEnv reads QUOTE_POOL_SIZE, QUOTE_POOL_WORKERS, QUOTE_POOL_RATE,
+// QUOTE_POOL_MAX_AGE and QUOTE_POOL_TIMEOUT. The pool covers the preset tags.
+func ConfigFromEnv() Config {
+	config := Config{
+		Workers: 2,
+		Rate:    1,
+		MaxAge:  time.Hour,
+		Timeout: 30 * time.Second,
+		Tags:    models.ValidTags,
+	}
+
+	readInt := func(key string, target *int, min int) {
+		value := os.Getenv(key)
+		if value == "" {
+			return
+		}
+		if parsed, err := strconv.Atoi(value); err == nil && parsed >= min {
+			*target = parsed
+		} else {
+			log.Printf("Warning: invalid %s %q, using %d", key, value, *target)
+		}
+	}
+	readDuration := func(key string, target *time.Duration) {
+		value := os.Getenv(key)
+		if value == "" {
+			return
+		}
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			*target = parsed
+		} else {
+			log.Printf("Warning: invalid %s %q, using %s", key, value, *target)
+		}
+	}
+
+	readInt("QUOTE_POOL_SIZE", &config.Size, 0)
+	readInt("QUOTE_POOL_WORKERS", &config.Workers, 1)
+	if value := os.Getenv("QUOTE_POOL_RATE"); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil && parsed > 0 {
+			config.Rate = parsed
+		} else {
+			log.Printf("Warning: invalid QUOTE_POOL_RATE %q, using %g", value, config.Rate)
+		}
+	}
+	readDuration("QUOTE_POOL_MAX_AGE", &config.MaxAge)
+	readDuration("QUOTE_POOL_TIMEOUT", &config.Timeout)
+
+	return config
+}
+
+// Enabled reports whether the pool should run
+func (c Config) Enabled() bool {
+	return c.Size > 0 && len(c.Tags) > 0
+}
+
+// Entry is a pooled quote
+type Entry struct {
+	Result *client.GenerationResult
+	// Latency is how long the generation took
+	Latency     time.Duration
+	GeneratedAt time.Time
+}
+
+// Pool keeps up to Size unserved quotes per tag, refilled in the background
+type Pool struct {
+	generator client.QuoteGenerator
+	config    Config
+	// Budget, when set, is checked before and charged after every refill
+	Budget *budget.Tracker
+
+	mu      sync.Mutex
+	entries map[string][]Entry
+	pending map[string]int
+	now     func() time.Time
+
+	jobs    chan string
+	refill  chan struct{}
+	limiter *limiter
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// New creates a pool filled by generator; call Start to begin refilling.
+// Refills bypass request coalescing so concurrent workers never share a quote.
+func New(generator client.QuoteGenerator, config Config) *Pool {
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	if coalescing, ok := generator.(*client.CoalescingGenerator); ok {
+		generator = coalescing.QuoteGenerator
+	}
+	p := &Pool{
+		generator: generator,
+		config:    config,
+		entries:   make(map[string][]Entry),
+		pending:   make(map[string]int),
+		now:       time.Now,
+		jobs:      make(chan string, len(config.Tags)*config.Size),
+		refill:    make(chan struct{}, 1),
+		limiter:   newLimiter(config.Rate),
+	}
+	for _, tag := range config.Tags {
+		metrics.SetPoolDepth(tag, 0)
+	}
+	return p
+}
+
+// SetClock replaces the pool's clock, for tests
+func (p *Pool) SetClock(now func() time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.now = now
+}
+
+// Start launches the scheduler and the refill workers
+func (p *Pool) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+
+	p.wg.Add(1)
+	go p.schedule(ctx)
+	for i := 0; i < p.config.Workers; i++ {
+		p.wg.Add(1)
+		go p.work(ctx)
+	}
+	log.Printf("Quote pool started: %d quotes for %d tags, %d workers", p.config.Size, len(p.config.Tags), p.config.Workers)
+}
+
+// Stop cancels in-flight refills and waits for the workers to exit
+func (p *Pool) Stop() {
+	if p.cancel == nil {
+		return
+	}
+	p.cancel()
+	p.wg.Wait()
+	log.Println("Quote pool stopped")
+}
+
+// Covers reports whether the pool keeps quotes for the tag
+func (p *Pool) Covers(tag string) bool {
+	for _, t := range p.config.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Take removes and returns the oldest fresh quote for the tag, or false when
+// the tag's pool is empty
+func (p *Pool) Take(tag string) (Entry, bool) {
+	if !p.Covers(tag) {
+		return Entry{}, false
+	}
+
+	p.mu.Lock()
+	p.evictStale(tag)
+	entries := p.entries[tag]
+	var entry Entry
+	ok := len(entries) > 0
+	if ok {
+		entry = entries[0]
+		p.entries[tag] = entries[1:]
+	}
+	depth := len(p.entries[tag])
+	p.mu.Unlock()
+
+	metrics.SetPoolDepth(tag, depth)
+	if !ok {
+		metrics.RecordPoolMiss(tag)
+	}
+	p.requestRefill()
+	return entry, ok
+}
+
+// Depth returns the number of pooled quotes for the tag
+func (p *Pool) Depth(tag string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.entries[tag])
+}
+
+// requestRefill wakes the scheduler without blocking
+func (p *Pool) requestRefill() {
+	select {
+	case p.refill <- struct{}{}:
+	default:
+	}
+}
+
+// schedule queues refills whenever quotes are taken and periodically, so
+// stale quotes are replaced and failed refills are retried
+func (p *Pool) schedule(ctx context.Context) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.tickInterval())
+	defer ticker.Stop()
+
+	for {
+		p.enqueue()
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.refill:
+		case <-ticker.C:
+		}
+	}
+}
+
+// tickInterval is how often the scheduler looks for stale quotes and missing refills
+func (p *Pool) tickInterval() time.Duration {
+	interval := p.config.MaxAge / 4
+	if interval <= 0 || interval > time.Minute {
+		interval = time.Minute
+	}
+	return interval
+}
+
+// enqueue queues a refill job for every missing quote
+func (p *Pool) enqueue() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, tag := range p.config.Tags {
+		p.evictStale(tag)
+		metrics.SetPoolDepth(tag, len(p.entries[tag]))
+		for missing := p.config.Size - len(p.entries[tag]) - p.pending[tag]; missing > 0; missing-- {
+			select {
+			case p.jobs <- tag:
+				p.pending[tag]++
+			default:
+				return
+			}
+		}
+	}
+}
+
+// evictStale drops quotes older than MaxAge; callers must hold mu
+func (p *Pool) evictStale(tag string) {
+	if p.config.MaxAge <= 0 {
+		return
+	}
+	entries := p.entries[tag]
+	cutoff := p.now().Add(-p.config.MaxAge)
+	fresh := 0
+	for fresh < len(entries) && entries[fresh].GeneratedAt.Before(cutoff) {
+		fresh++
+	}
+	p.entries[tag] = entries[fresh:]
+}
+
+// work runs refill jobs until the pool stops
+func (p *Pool) work(ctx context.Context) {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case tag := <-p.jobs:
+			p.fill(ctx, tag)
+		}
+	}
+}
+
+// recordUsage charges the usage of a refill to the global budget
+func (p *Pool) recordUsage(usage client.Usage, costUSD float64) {
+	if p.Budget == nil {
+		return
+	}
+
+	tokens := usage.TotalTokens
+	if tokens == 0 {
+		tokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	if err := p.Budget.Record(context.Background(), "", tokens, costUSD); err != nil {
+		log.Printf("Error recording budget usage: %v", err)
+	}
+}
+
+// fill generates one quote for the tag within the rate and budget limits
+func (p *Pool) fill(ctx context.Context, tag string) {
+	defer func() {
+		p.mu.Lock()
+		p.pending[tag]--
+		p.mu.Unlock()
+	}()
+
+	if err := p.limiter.wait(ctx); err != nil {
+		return
+	}
+
+	if p.Budget != nil {
+		if err := p.Budget.Check(ctx, ""); err != nil {
+			var exceeded *budget.ExceededError
+			if !errors.As(err, &exceeded) {
+				log.Printf("Error checking budget for quote pool: %v", err)
+			}
+			return
+		}
+	}
+
+	genCtx, cancel := context.WithTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	result, err := p.generator.GenerateQuote(genCtx, tag, client.GenerateOptions{})
+	latency := time.Since(start)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Error refilling quote pool for %s: %v", tag, err)
+		}
+		p.recordUsage(client.SpentUsage(err))
+		return
+	}
+	metrics.RecordPoolRefill(latency.Seconds())
+	p.recordUsage(result.Usage, result.CostUSD)
+
+	p.mu.Lock()
+	p.entries[tag] = append(p.entries[tag], Entry{
+		Result:      result,
+		Latency:     latency,
+		GeneratedAt: p.now(),
+	})
+	depth := len(p.entries[tag])
+	p.mu.Unlock()
+	metrics.SetPoolDepth(tag, depth)
+}
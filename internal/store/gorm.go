@@ -0,0 +1,196 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Adeel56/quotebox/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// statsColumns maps the stats groups to their SQL expressions
+var statsColumns = map[string]string{
+	GroupDay:   "DATE(created_at)",
+	GroupModel: "model",
+	GroupTag:   "tag",
+}
+
+// statsAggregates are the aggregated columns of every stats row
+var statsAggregates = []string{
+	"COUNT(*) AS quotes",
+	"COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens",
+	"COALESCE(SUM(completion_tokens), 0) AS completion_tokens",
+	"COALESCE(SUM(total_tokens), 0) AS total_tokens",
+	"COALESCE(SUM(cost_usd), 0) AS cost_usd",
+}
+
+// GormStore keeps quotes in the quotes table
+type GormStore struct {
+	DB *gorm.DB
+}
+
+// NewGormStore creates a store backed by the given database
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{DB: db}
+}
+
+// Create stores a new quote
+func (s *GormStore) Create(ctx context.Context, quote *models.Quote) error {
+	return s.DB.WithContext(ctx).Create(quote).Error
+}
+
+// Get returns the quote with the given ID
+func (s *GormStore) Get(ctx context.Context, id uuid.UUID) (*models.Quote, error) {
+	var quote models.Quote
+	err := s.DB.WithContext(ctx).Where("id = ?", id).First(&quote).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &quote, nil
+}
+
+// List returns the quotes matching the filter
+func (s *GormStore) List(ctx context.Context, filter QuoteFilter) ([]models.Quote, error) {
+	query := s.DB.WithContext(ctx)
+	if filter.Oldest {
+		query = query.Order("created_at")
+	} else {
+		query = query.Order("created_at DESC")
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	conditions := []struct {
+		column, value string
+		// optional fields also match empty values with MatchEmpty
+		optional bool
+	}{
+		{"tag", filter.Tag, false},
+		{"model", filter.Model, true},
+		{"prompt_version", filter.PromptVersion, false},
+		{"tone", filter.Tone, true},
+		{"length", filter.Length, true},
+		{"language", filter.Language, true},
+		{"audience", filter.Audience, true},
+		{"text_hash", filter.TextHash, false},
+	}
+	for _, condition := range conditions {
+		switch {
+		case condition.value != "":
+			query = query.Where(condition.column+" = ?", condition.value)
+		case condition.optional && filter.MatchEmpty:
+			// Nullable columns such as language count NULL as empty
+			query = query.Where("COALESCE(" + condition.column + ", '') = ''")
+		}
+	}
+
+	var quotes []models.Quote
+	if err := query.Find(&quotes).Error; err != nil {
+		return nil, err
+	}
+	return quotes, nil
+}
+
+// Update saves the editable fields of the quote
+func (s *GormStore) Update(ctx context.Context, quote *models.Quote) error {
+	result := s.DB.WithContext(ctx).Model(&models.Quote{}).
+		Where("id = ?", quote.ID).
+		Select(EditableColumns).
+		Updates(quote)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete soft-deletes the quote with the given ID
+func (s *GormStore) Delete(ctx context.Context, id uuid.UUID) error {
+	result := s.DB.WithContext(ctx).Where("id = ?", id).Delete(&models.Quote{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Restore undeletes the quote with the given ID
+func (s *GormStore) Restore(ctx context.Context, id uuid.UUID) (*models.Quote, error) {
+	result := s.DB.WithContext(ctx).Unscoped().Model(&models.Quote{}).
+		Where("id = ?", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+	return s.Get(ctx, id)
+}
+
+// Stats aggregates the usage of the quotes in the query's range with SQL
+func (s *GormStore) Stats(ctx context.Context, q StatsQuery) (Stats, error) {
+	columns := append([]string{}, statsAggregates...)
+	var groups []string
+	for _, group := range q.GroupBy {
+		expression, ok := statsColumns[group]
+		if !ok {
+			return Stats{}, fmt.Errorf("unknown stats group %q", group)
+		}
+		columns = append(columns, expression+" AS "+group)
+		groups = append(groups, expression)
+	}
+
+	query := s.DB.WithContext(ctx).Unscoped().Model(&models.Quote{}).
+		Where("created_at >= ? AND created_at < ?", q.From, q.To)
+
+	stats := Stats{Rows: []StatsRow{}}
+	if err := query.Session(&gorm.Session{}).Select(strings.Join(statsAggregates, ", ")).Scan(&stats.Totals).Error; err != nil {
+		return Stats{}, err
+	}
+	if len(groups) == 0 {
+		return stats, nil
+	}
+
+	grouped := query.Select(strings.Join(columns, ", ")).Group(strings.Join(groups, ", ")).Order(strings.Join(groups, ", "))
+	if err := grouped.Scan(&stats.Rows).Error; err != nil {
+		return Stats{}, err
+	}
+
+	// Drivers return dates as either "2006-01-02" or a full timestamp
+	for i := range stats.Rows {
+		if len(stats.Rows[i].Day) > len(DayLayout) {
+			stats.Rows[i].Day = stats.Rows[i].Day[:len(DayLayout)]
+		}
+	}
+	return stats, nil
+}
+
+// Ping checks the database connection
+func (s *GormStore) Ping(ctx context.Context) error {
+	sqlDB, err := s.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// Close closes the database connection
+func (s *GormStore) Close() error {
+	sqlDB, err := s.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
@@ -0,0 +1,209 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Adeel56/quotebox/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MemoryStore keeps quotes in memory; quotes are lost on restart
+type MemoryStore struct {
+	mu     sync.RWMutex
+	quotes map[uuid.UUID]models.Quote
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{quotes: make(map[uuid.UUID]models.Quote)}
+}
+
+// Create stores a copy of the quote, setting its ID and creation time when unset
+func (s *MemoryStore) Create(ctx context.Context, quote *models.Quote) error {
+	if quote.ID == uuid.Nil {
+		quote.ID = uuid.New()
+	}
+	if quote.CreatedAt.IsZero() {
+		quote.CreatedAt = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.quotes[quote.ID]; ok {
+		return fmt.Errorf("quote %s already exists", quote.ID)
+	}
+	s.quotes[quote.ID] = *quote
+	return nil
+}
+
+// Get returns a copy of the quote with the given ID
+func (s *MemoryStore) Get(ctx context.Context, id uuid.UUID) (*models.Quote, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	quote, ok := s.quotes[id]
+	if !ok || quote.DeletedAt.Valid {
+		return nil, ErrNotFound
+	}
+	return &quote, nil
+}
+
+// List returns copies of the quotes matching the filter
+func (s *MemoryStore) List(ctx context.Context, filter QuoteFilter) ([]models.Quote, error) {
+	s.mu.RLock()
+	quotes := []models.Quote{}
+	for _, quote := range s.quotes {
+		if !quote.DeletedAt.Valid && filter.matches(quote) {
+			quotes = append(quotes, quote)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(quotes, func(i, j int) bool {
+		if filter.Oldest {
+			return quotes[i].CreatedAt.Before(quotes[j].CreatedAt)
+		}
+		return quotes[i].CreatedAt.After(quotes[j].CreatedAt)
+	})
+	if filter.Limit > 0 && len(quotes) > filter.Limit {
+		quotes = quotes[:filter.Limit]
+	}
+	return quotes, nil
+}
+
+// matches reports whether the quote passes the filter
+func (f QuoteFilter) matches(q models.Quote) bool {
+	language := ""
+	if q.Language != nil {
+		language = *q.Language
+	}
+	conditions := []struct {
+		want, got string
+		optional  bool
+	}{
+		{f.Tag, q.Tag, false},
+		{f.Model, q.Model, true},
+		{f.PromptVersion, q.PromptVersion, false},
+		{f.Tone, q.Tone, true},
+		{f.Length, q.Length, true},
+		{f.Language, language, true},
+		{f.Audience, q.Audience, true},
+		{f.TextHash, q.TextHash, false},
+	}
+	for _, condition := range conditions {
+		if (condition.want != "" || (condition.optional && f.MatchEmpty)) && condition.want != condition.got {
+			return false
+		}
+	}
+	return true
+}
+
+// Update saves the editable fields of the quote
+func (s *MemoryStore) Update(ctx context.Context, quote *models.Quote) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.quotes[quote.ID]
+	if !ok || stored.DeletedAt.Valid {
+		return ErrNotFound
+	}
+	stored.QuoteText = quote.QuoteText
+	stored.TextHash = quote.TextHash
+	stored.Author = quote.Author
+	stored.Tag = quote.Tag
+	stored.TagSource = quote.TagSource
+	s.quotes[quote.ID] = stored
+	return nil
+}
+
+// Delete marks the quote with the given ID as deleted
+func (s *MemoryStore) Delete(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	quote, ok := s.quotes[id]
+	if !ok || quote.DeletedAt.Valid {
+		return ErrNotFound
+	}
+	quote.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	s.quotes[id] = quote
+	return nil
+}
+
+// Restore clears the deletion mark of the quote with the given ID
+func (s *MemoryStore) Restore(ctx context.Context, id uuid.UUID) (*models.Quote, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	quote, ok := s.quotes[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	quote.DeletedAt = gorm.DeletedAt{}
+	s.quotes[id] = quote
+	return &quote, nil
+}
+
+// Stats aggregates the usage of the quotes in the query's range; days are in UTC
+func (s *MemoryStore) Stats(ctx context.Context, q StatsQuery) (Stats, error) {
+	for _, group := range q.GroupBy {
+		if !IsGroup(group) {
+			return Stats{}, fmt.Errorf("unknown stats group %q", group)
+		}
+	}
+
+	stats := Stats{Rows: []StatsRow{}}
+	rows := make(map[string]*StatsRow)
+	var keys []string
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, quote := range s.quotes {
+		if quote.CreatedAt.Before(q.From) || !quote.CreatedAt.Before(q.To) {
+			continue
+		}
+		addUsage(&stats.Totals, quote)
+		if len(q.GroupBy) == 0 {
+			continue
+		}
+
+		var row StatsRow
+		fields := make([]string, len(q.GroupBy))
+		for i, group := range q.GroupBy {
+			switch group {
+			case GroupDay:
+				row.Day = quote.CreatedAt.UTC().Format(DayLayout)
+				fields[i] = row.Day
+			case GroupModel:
+				row.Model = quote.Model
+				fields[i] = row.Model
+			case GroupTag:
+				row.Tag = quote.Tag
+				fields[i] = row.Tag
+			}
+		}
+		key := strings.Join(fields, "\x00")
+		if rows[key] == nil {
+			rows[key] = &row
+			keys = append(keys, key)
+		}
+		addUsage(rows[key], quote)
+	}
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		stats.Rows = append(stats.Rows, *rows[key])
+	}
+	return stats, nil
+}
+
+// addUsage adds the quote's usage to the row
+func addUsage(row *StatsRow, q models.Quote) {
+	row.Quotes++
+	row.PromptTokens += int64(q.PromptTokens)
+	row.CompletionTokens += int64(q.CompletionTokens)
+	row.TotalTokens += int64(q.TotalTokens)
+	row.CostUSD += q.CostUSD
+}
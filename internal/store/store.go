@@ -0,0 +1,109 @@
+// Package store persists generated quotes behind the QuoteStore interface so
+// handlers do not depend on a particular database.
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Adeel56/quotebox/internal/models"
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned when no stored quote has the requested ID
+var ErrNotFound = errors.New("quote not found")
+
+// Groups accepted by StatsQuery.GroupBy
+const (
+	GroupDay   = "day"
+	GroupModel = "model"
+	GroupTag   = "tag"
+)
+
+// DayLayout is the format of StatsRow.Day
+const DayLayout = "2006-01-02"
+
+// QuoteStore stores generated quotes. Deleted quotes are kept but hidden from
+// Get, List, Update and Delete until restored.
+type QuoteStore interface {
+	// Create stores a new quote, assigning its ID when unset
+	Create(ctx context.Context, quote *models.Quote) error
+	// Get returns the quote with the given ID or ErrNotFound
+	Get(ctx context.Context, id uuid.UUID) (*models.Quote, error)
+	// List returns the quotes matching the filter, newest first
+	List(ctx context.Context, filter QuoteFilter) ([]models.Quote, error)
+	// Update saves the editable fields of a quote, see EditableColumns, or returns ErrNotFound
+	Update(ctx context.Context, quote *models.Quote) error
+	// Delete soft-deletes the quote with the given ID or returns ErrNotFound
+	Delete(ctx context.Context, id uuid.UUID) error
+	// Restore undeletes the quote with the given ID and returns it, or ErrNotFound
+	Restore(ctx context.Context, id uuid.UUID) (*models.Quote, error)
+	// Stats aggregates the usage of the quotes created in the query's range,
+	// including deleted ones since their tokens were spent
+	Stats(ctx context.Context, query StatsQuery) (Stats, error)
+}
+
+// EditableColumns are the quote columns saved by Update
+var EditableColumns = []string{"quote_text", "text_hash", "author", "tag", "tag_source"}
+
+// Pinger is implemented by stores that can report whether their backend is reachable
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// QuoteFilter selects stored quotes; empty fields match any value
+type QuoteFilter struct {
+	Tag           string
+	Model         string
+	PromptVersion string
+	Tone          string
+	Length        string
+	Language      string
+	Audience      string
+	TextHash      string
+	// MatchEmpty makes empty Model, Tone, Length, Language and Audience match
+	// only quotes without them
+	MatchEmpty bool
+	// Oldest returns the oldest quotes first instead of the newest
+	Oldest bool
+	// Limit bounds the number of quotes returned; 0 returns all of them
+	Limit int
+}
+
+// StatsQuery selects the quotes aggregated by Stats
+type StatsQuery struct {
+	// From is inclusive and To exclusive
+	From time.Time
+	To   time.Time
+	// GroupBy lists the groups of the rows, any of GroupDay, GroupModel and GroupTag
+	GroupBy []string
+}
+
+// StatsRow is one aggregated row of usage
+type StatsRow struct {
+	Day              string  `json:"day,omitempty"`
+	Model            string  `json:"model,omitempty"`
+	Tag              string  `json:"tag,omitempty"`
+	Quotes           int64   `json:"quotes"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// Stats is the usage of a range of quotes, per group and in total
+type Stats struct {
+	// Rows are ordered by their groups; empty when nothing is grouped
+	Rows   []StatsRow
+	Totals StatsRow
+}
+
+// IsGroup reports whether group is a valid StatsQuery group
+func IsGroup(group string) bool {
+	switch group {
+	case GroupDay, GroupModel, GroupTag:
+		return true
+	}
+	return false
+}
@@ -7,7 +7,7 @@ set -e
 echo "Initializing database..."
 
 # This script can be used to seed the database with initial data
-# For now, GORM AutoMigrate handles schema creation
+# The schema is created by the versioned migrations (quotebox migrate up)
 
 # Example: Insert some sample quotes
 # psql $DATABASE_URL <<-EOSQL
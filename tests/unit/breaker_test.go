@@ -0,0 +1,86 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Adeel56/quotebox/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker_Transitions(t *testing.T) {
+	b := client.NewCircuitBreaker("test", client.BreakerConfig{
+		FailureThreshold: 2,
+		CoolDown:         20 * time.Millisecond,
+	})
+
+	assert.Equal(t, client.BreakerClosed, b.State())
+	assert.True(t, b.Allow())
+
+	// Opens after the threshold of consecutive failures
+	b.Failure()
+	assert.Equal(t, client.BreakerClosed, b.State())
+	b.Failure()
+	assert.Equal(t, client.BreakerOpen, b.State())
+	assert.False(t, b.Allow())
+
+	// Lets a single trial call through once the cool-down has elapsed
+	time.Sleep(30 * time.Millisecond)
+	assert.True(t, b.Ready())
+	assert.True(t, b.Allow())
+	assert.Equal(t, client.BreakerHalfOpen, b.State())
+	assert.False(t, b.Allow())
+
+	// A failed trial opens the circuit again
+	b.Failure()
+	assert.Equal(t, client.BreakerOpen, b.State())
+
+	// A successful trial closes it
+	time.Sleep(30 * time.Millisecond)
+	assert.True(t, b.Allow())
+	b.Success()
+	assert.Equal(t, client.BreakerClosed, b.State())
+}
+
+func TestBreakerGenerator_FailsFastWhenOpen(t *testing.T) {
+	upstream := &stubGenerator{name: "upstream", err: errors.New("timeout"), available: true}
+	gen := client.WithCircuitBreaker(upstream, client.BreakerConfig{
+		FailureThreshold: 1,
+		CoolDown:         time.Minute,
+	})
+
+	_, err := gen.GenerateQuote(context.Background(), "joy", client.GenerateOptions{})
+	assert.Error(t, err)
+	assert.False(t, gen.Available())
+
+	_, err = gen.GenerateQuote(context.Background(), "joy", client.GenerateOptions{})
+	assert.ErrorIs(t, err, client.ErrCircuitOpen)
+	assert.Equal(t, 1, upstream.calls)
+
+	statuses := gen.ProviderStatuses()
+	require.Len(t, statuses, 1)
+	assert.Equal(t, "open", statuses[0].Circuit)
+}
+
+func TestBreakerGenerator_RejectionsDoNotTrip(t *testing.T) {
+	upstream := &stubGenerator{name: "upstream", available: true, err: &client.UsageError{
+		Err:   &client.RejectionError{Reason: client.RejectTooShort, Text: "Hi."},
+		Usage: client.Usage{TotalTokens: 20},
+	}}
+	gen := client.WithCircuitBreaker(upstream, client.BreakerConfig{
+		FailureThreshold: 1,
+		CoolDown:         time.Minute,
+	})
+
+	for i := 0; i < 3; i++ {
+		_, err := gen.GenerateQuote(context.Background(), "joy", client.GenerateOptions{})
+		var rejection *client.RejectionError
+		assert.True(t, errors.As(err, &rejection))
+	}
+	assert.Equal(t, 3, upstream.calls)
+	assert.True(t, gen.Available())
+	assert.Equal(t, client.BreakerClosed, gen.Breaker.State())
+}
@@ -0,0 +1,211 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Adeel56/quotebox/internal/app/handlers"
+	"github.com/Adeel56/quotebox/internal/budget"
+	"github.com/Adeel56/quotebox/internal/client"
+	"github.com/Adeel56/quotebox/internal/metrics"
+	"github.com/Adeel56/quotebox/internal/store"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBudgetConfigFromEnv(t *testing.T) {
+	t.Setenv("BUDGET_MONTHLY_USD", "25.5")
+	t.Setenv("BUDGET_CONSUMER_DAILY_TOKENS", "5000")
+	t.Setenv("BUDGET_DAILY_TOKENS", "-1")
+	t.Setenv("BUDGET_EXCEEDED_ACTION", "reject")
+	t.Setenv("BUDGET_FALLBACK_PROVIDERS", "ollama, corpus")
+	t.Setenv("BUDGET_CONSUMER_KEYS", "alice:k1, bad id:k2,bob:,carol:k3")
+
+	config := budget.ConfigFromEnv()
+	assert.True(t, config.Enabled())
+	assert.Equal(t, 25.5, config.Global.Monthly.CostUSD)
+	assert.Equal(t, int64(0), config.Global.Daily.Tokens)
+	assert.Equal(t, int64(5000), config.Consumer.Daily.Tokens)
+	assert.Equal(t, budget.ActionReject, config.Action)
+	assert.Equal(t, []string{"ollama", "corpus"}, config.FallbackProviders)
+	assert.Equal(t, map[string]string{"k1": "alice", "k3": "carol"}, config.ConsumerKeys)
+}
+
+func TestBudgetConfig_DisabledByDefault(t *testing.T) {
+	config := budget.ConfigFromEnv()
+	assert.False(t, config.Enabled())
+	assert.Equal(t, budget.ActionFallback, config.Action)
+	assert.Equal(t, []string{"corpus"}, config.FallbackProviders)
+}
+
+func TestTracker_GlobalBudget(t *testing.T) {
+	ctx := context.Background()
+	tracker := budget.NewTracker(budget.NewMemoryStore(), budget.Config{
+		Global: budget.Limits{Daily: budget.Limit{CostUSD: 0.01}},
+	})
+	now := time.Date(2026, 3, 14, 15, 0, 0, 0, time.UTC)
+	tracker.SetClock(func() time.Time { return now })
+
+	require.NoError(t, tracker.Check(ctx, "alice"))
+	require.NoError(t, tracker.Record(ctx, "alice", 500, 0.006))
+	require.NoError(t, tracker.Check(ctx, "bob"))
+	require.NoError(t, tracker.Record(ctx, "bob", 500, 0.006))
+
+	err := tracker.Check(ctx, "carol")
+	var exceeded *budget.ExceededError
+	require.True(t, errors.As(err, &exceeded))
+	assert.Equal(t, budget.ScopeGlobal, exceeded.Scope)
+	assert.Equal(t, budget.PeriodDay, exceeded.Period)
+	assert.Equal(t, "usd", exceeded.Unit)
+	assert.Equal(t, time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC), exceeded.ResetAt)
+
+	assert.Equal(t, 0.012, testutil.ToFloat64(metrics.QuoteBudgetUsed.WithLabelValues(budget.PeriodDay, "usd")))
+
+	// A new day starts with a fresh budget
+	now = now.Add(12 * time.Hour)
+	assert.NoError(t, tracker.Check(ctx, "carol"))
+}
+
+func TestTracker_ConsumerBudget(t *testing.T) {
+	ctx := context.Background()
+	tracker := budget.NewTracker(budget.NewMemoryStore(), budget.Config{
+		Consumer: budget.Limits{Monthly: budget.Limit{Tokens: 1000}},
+	})
+	now := time.Date(2026, 12, 20, 8, 0, 0, 0, time.UTC)
+	tracker.SetClock(func() time.Time { return now })
+
+	require.NoError(t, tracker.Record(ctx, "alice", 1000, 0))
+
+	err := tracker.Check(ctx, "alice")
+	var exceeded *budget.ExceededError
+	require.True(t, errors.As(err, &exceeded))
+	assert.Equal(t, budget.ScopeConsumer, exceeded.Scope)
+	assert.Equal(t, budget.PeriodMonth, exceeded.Period)
+	assert.Equal(t, "tokens", exceeded.Unit)
+	assert.Equal(t, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), exceeded.ResetAt)
+
+	// Other consumers are unaffected
+	assert.NoError(t, tracker.Check(ctx, "bob"))
+}
+
+func TestTracker_BoundsConsumerKeys(t *testing.T) {
+	ctx := context.Background()
+	usage := &keyRecordingStore{Store: budget.NewMemoryStore()}
+	tracker := budget.NewTracker(usage, budget.Config{
+		Consumer: budget.Limits{Daily: budget.Limit{Tokens: 100}},
+	})
+
+	long := strings.Repeat("x", 500)
+	require.NoError(t, tracker.Record(ctx, long, 100, 0))
+	var exceeded *budget.ExceededError
+	assert.True(t, errors.As(tracker.Check(ctx, long), &exceeded))
+	assert.NoError(t, tracker.Check(ctx, strings.Repeat("x", 499)))
+
+	require.NotEmpty(t, usage.keys)
+	for _, key := range usage.keys {
+		assert.LessOrEqual(t, len(key), 150, "keys fit the budget_usages.key column")
+	}
+}
+
+// keyRecordingStore records the keys added to the wrapped store
+type keyRecordingStore struct {
+	budget.Store
+	keys []string
+}
+
+func (s *keyRecordingStore) Add(ctx context.Context, key, period string, start time.Time, usage budget.Usage) (budget.Usage, error) {
+	s.keys = append(s.keys, key)
+	return s.Store.Add(ctx, key, period, start, usage)
+}
+
+func TestQuoteHandler_KeysConsumerBudgetsOnAPIKeys(t *testing.T) {
+	handler := handlers.NewQuoteHandler(&stubResultGenerator{result: &client.GenerationResult{
+		Text:  "Hope is a waking dream.",
+		Usage: client.Usage{TotalTokens: 100},
+	}}, store.NewMemoryStore())
+	handler.Budget = budget.NewTracker(budget.NewMemoryStore(), budget.Config{
+		Consumer:     budget.Limits{Daily: budget.Limit{Tokens: 100}},
+		Action:       budget.ActionReject,
+		ConsumerKeys: map[string]string{"secret-key": "alice"},
+	})
+	router := newQuoteRouter(handler)
+
+	create := func(header, value string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/quote", strings.NewReader(`{"tag":"hope","requestor":"`+value+`"}`))
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	require.Equal(t, http.StatusOK, create("", "anonymous"))
+	// Self-declared IDs do not escape the client's own budget
+	assert.Equal(t, http.StatusTooManyRequests, create("X-Consumer-ID", "someone-else"))
+	assert.Equal(t, http.StatusTooManyRequests, create("X-API-Key", "wrong-key"))
+	// A consumer with a valid key has its own budget
+	assert.Equal(t, http.StatusOK, create("X-API-Key", "secret-key"))
+	assert.Equal(t, http.StatusTooManyRequests, create("X-API-Key", "secret-key"))
+}
+
+func TestNewTracker_LoadsUsageMetrics(t *testing.T) {
+	ctx := context.Background()
+	usage := budget.NewMemoryStore()
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	_, err := usage.Add(ctx, "global", budget.PeriodDay, today, budget.Usage{Tokens: 700, CostUSD: 0.07})
+	require.NoError(t, err)
+
+	budget.NewTracker(usage, budget.Config{Global: budget.Limits{Daily: budget.Limit{Tokens: 1000}}})
+
+	assert.Equal(t, float64(700), testutil.ToFloat64(metrics.QuoteBudgetUsed.WithLabelValues(budget.PeriodDay, "tokens")))
+	assert.Equal(t, 0.07, testutil.ToFloat64(metrics.QuoteBudgetUsed.WithLabelValues(budget.PeriodDay, "usd")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.QuoteBudgetUsed.WithLabelValues(budget.PeriodMonth, "tokens")))
+}
+
+func TestQuoteHandler_RecordsUsageOfFailedGenerations(t *testing.T) {
+	ctx := context.Background()
+	usage := budget.NewMemoryStore()
+	handler := handlers.NewQuoteHandler(&stubGenerator{
+		name: "rejecting",
+		err: &client.UsageError{
+			Err:     &client.RejectionError{Reason: client.RejectRefusal},
+			Usage:   client.Usage{TotalTokens: 90},
+			CostUSD: 0.03,
+		},
+	}, store.NewMemoryStore())
+	handler.Budget = budget.NewTracker(usage, budget.Config{Global: budget.Limits{Daily: budget.Limit{Tokens: 1000}}})
+	router := newQuoteRouter(handler)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/quote", strings.NewReader(`{"tag":"hope"}`)))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	now := time.Now().UTC()
+	spent, err := usage.Usage(ctx, "global", budget.PeriodDay, time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, budget.Usage{Tokens: 90, CostUSD: 0.03}, spent)
+}
+
+func TestMemoryStore_Add(t *testing.T) {
+	ctx := context.Background()
+	store := budget.NewMemoryStore()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := store.Add(ctx, "global", budget.PeriodMonth, start, budget.Usage{Tokens: 10, CostUSD: 0.5})
+	require.NoError(t, err)
+	total, err := store.Add(ctx, "global", budget.PeriodMonth, start, budget.Usage{Tokens: 5, CostUSD: 0.25})
+	require.NoError(t, err)
+	assert.Equal(t, budget.Usage{Tokens: 15, CostUSD: 0.75}, total)
+
+	usage, err := store.Usage(ctx, "global", budget.PeriodDay, start)
+	require.NoError(t, err)
+	assert.Equal(t, budget.Usage{}, usage)
+}
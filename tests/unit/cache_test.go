@@ -0,0 +1,227 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Adeel56/quotebox/internal/app/handlers"
+	"github.com/Adeel56/quotebox/internal/cache"
+	"github.com/Adeel56/quotebox/internal/client"
+	"github.com/Adeel56/quotebox/internal/db"
+	"github.com/Adeel56/quotebox/internal/metrics"
+	"github.com/Adeel56/quotebox/internal/models"
+	"github.com/Adeel56/quotebox/internal/store"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheConfigFromEnv(t *testing.T) {
+	t.Setenv("QUOTE_CACHE", "Memory")
+	t.Setenv("QUOTE_CACHE_SIZE", "50")
+	t.Setenv("QUOTE_CACHE_TTL", "soon")
+	t.Setenv("QUOTE_CACHE_REUSE_STORED", "true")
+	t.Setenv("QUOTE_CACHE_REUSE_AFTER", "1h")
+
+	config := cache.ConfigFromEnv()
+	assert.Equal(t, cache.BackendMemory, config.Backend)
+	assert.Equal(t, 50, config.Size)
+	assert.Equal(t, 10*time.Minute, config.TTL)
+	assert.True(t, config.ReuseStored)
+	assert.Equal(t, time.Hour, config.ReuseAfter)
+}
+
+func TestNewBackend(t *testing.T) {
+	backend, err := cache.NewBackend(cache.Config{Backend: cache.BackendOff})
+	require.NoError(t, err)
+	assert.Nil(t, backend)
+
+	backend, err = cache.NewBackend(cache.Config{Backend: cache.BackendMemory, Size: 10})
+	require.NoError(t, err)
+	assert.IsType(t, &cache.LRU{}, backend)
+
+	_, err = cache.NewBackend(cache.Config{Backend: "memcached"})
+	assert.Error(t, err)
+}
+
+func TestLRU_Expiry(t *testing.T) {
+	ctx := context.Background()
+	lru := cache.NewLRU(10)
+	now := time.Date(2026, 3, 14, 15, 0, 0, 0, time.UTC)
+	lru.SetClock(func() time.Time { return now })
+
+	require.NoError(t, lru.Set(ctx, "key", []byte("value"), time.Minute))
+	value, ok, err := lru.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("value"), value)
+
+	now = now.Add(time.Minute)
+	_, ok, err = lru.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 0, lru.Len())
+}
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	lru := cache.NewLRU(2)
+
+	require.NoError(t, lru.Set(ctx, "a", []byte("1"), time.Hour))
+	require.NoError(t, lru.Set(ctx, "b", []byte("2"), time.Hour))
+	_, ok, _ := lru.Get(ctx, "a")
+	require.True(t, ok)
+	require.NoError(t, lru.Set(ctx, "c", []byte("3"), time.Hour))
+
+	_, ok, _ = lru.Get(ctx, "b")
+	assert.False(t, ok, "b was the least recently used entry")
+	_, ok, _ = lru.Get(ctx, "a")
+	assert.True(t, ok)
+	_, ok, _ = lru.Get(ctx, "c")
+	assert.True(t, ok)
+	assert.Equal(t, 2, lru.Len())
+}
+
+func TestQuoteCache_ServesStoredResponse(t *testing.T) {
+	metrics.QuoteCacheHitsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "quote_cache_hits_total_test",
+	}, []string{"source"})
+	metrics.QuoteCacheMissesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "quote_cache_misses_total_test",
+	})
+
+	ctx := context.Background()
+	quotes := store.NewMemoryStore()
+	quoteCache := handlers.NewQuoteCache(cache.NewLRU(10), cache.Config{TTL: time.Minute}, quotes)
+	opts := client.GenerateOptions{Tone: "stoic"}
+
+	response, _ := quoteCache.Lookup(ctx, "hope", opts)
+	assert.Nil(t, response)
+
+	quote := models.Quote{Tag: "hope", QuoteText: "Hope is a waking dream.", Tone: "stoic"}
+	require.NoError(t, quotes.Create(ctx, &quote))
+	stored := handlers.QuoteResponse{ID: quote.ID, Tag: "hope", Quote: "Hope is a waking dream.", Tone: "stoic"}
+	quoteCache.Store(ctx, "hope", opts, stored)
+
+	response, source := quoteCache.Lookup(ctx, "hope", opts)
+	require.NotNil(t, response)
+	assert.Equal(t, "memory", source)
+	assert.Equal(t, stored.ID, response.ID)
+	assert.Equal(t, stored.Quote, response.Quote)
+
+	// Other options are cached separately
+	response, _ = quoteCache.Lookup(ctx, "hope", client.GenerateOptions{Tone: "funny"})
+	assert.Nil(t, response)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.QuoteCacheHitsTotal.WithLabelValues("memory")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.QuoteCacheMissesTotal))
+}
+
+func TestQuoteCache_ReusesUnshownStoredQuote(t *testing.T) {
+	metrics.QuoteCacheHitsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "quote_cache_hits_total_test",
+	}, []string{"source"})
+	metrics.QuoteCacheMissesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "quote_cache_misses_total_test",
+	})
+
+	ctx := context.Background()
+	quotes := store.NewMemoryStore()
+	require.NoError(t, quotes.Create(ctx, &models.Quote{Tag: "hope", QuoteText: "Hope is a waking dream.", Tone: "stoic"}))
+	require.NoError(t, quotes.Create(ctx, &models.Quote{Tag: "hope", QuoteText: "Hope springs eternal."}))
+
+	quoteCache := handlers.NewQuoteCache(cache.NewLRU(10), cache.Config{ReuseStored: true, ReuseAfter: time.Hour}, quotes)
+
+	response, source := quoteCache.Lookup(ctx, "hope", client.GenerateOptions{})
+	require.NotNil(t, response)
+	assert.Equal(t, "database", source)
+	assert.Equal(t, "Hope springs eternal.", response.Quote, "only quotes without options match a request without options")
+
+	// A shown quote is not reused again
+	response, _ = quoteCache.Lookup(ctx, "hope", client.GenerateOptions{})
+	assert.Nil(t, response)
+}
+
+func TestQuoteCache_ReusesQuotesOfTheRequestedModelAndLanguage(t *testing.T) {
+	t.Setenv("DATABASE_URL", "sqlite://"+filepath.Join(t.TempDir(), "quotebox.db"))
+	database, err := db.Connect()
+	require.NoError(t, err)
+	gormStore := store.NewGormStore(database)
+	defer gormStore.Close()
+
+	for name, quotes := range map[string]store.QuoteStore{"memory": store.NewMemoryStore(), "gorm": gormStore} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			french := "fr"
+			for _, quote := range []models.Quote{
+				{Tag: "hope", TagSource: "preset", Source: "stub", QuoteText: "L'espoir est un rêve éveillé.", Language: &french},
+				{Tag: "hope", TagSource: "preset", Source: "stub", QuoteText: "Hope is a waking dream.", Model: "openai/gpt-4o"},
+			} {
+				quote := quote
+				require.NoError(t, quotes.Create(ctx, &quote))
+			}
+			quoteCache := handlers.NewQuoteCache(cache.NewLRU(10), cache.Config{ReuseStored: true, ReuseAfter: time.Hour}, quotes)
+
+			// Neither the French quote nor the one of another model answers a plain request
+			response, _ := quoteCache.Lookup(ctx, "hope", client.GenerateOptions{})
+			assert.Nil(t, response)
+
+			response, _ = quoteCache.Lookup(ctx, "hope", client.GenerateOptions{Language: "fr"})
+			require.NotNil(t, response)
+			assert.Equal(t, "L'espoir est un rêve éveillé.", response.Quote)
+
+			response, _ = quoteCache.Lookup(ctx, "hope", client.GenerateOptions{Model: "openai/gpt-4o"})
+			require.NotNil(t, response)
+			assert.Equal(t, "Hope is a waking dream.", response.Quote)
+		})
+	}
+}
+
+func TestQuoteHandler_CacheFollowsEditsAndDeletes(t *testing.T) {
+	quotes := store.NewMemoryStore()
+	handler := handlers.NewQuoteHandler(&stubResultGenerator{result: &client.GenerationResult{
+		Text: "Hope is a waking dream.",
+	}}, quotes)
+	handler.Cache = handlers.NewQuoteCache(cache.NewLRU(10), cache.Config{TTL: time.Minute}, quotes)
+	router := newQuoteRouter(handler)
+
+	create := func() (*httptest.ResponseRecorder, handlers.QuoteResponse) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/quote", strings.NewReader(`{"tag":"hope"}`)))
+		require.Equal(t, http.StatusOK, recorder.Code)
+		var response handlers.QuoteResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		return recorder, response
+	}
+
+	_, first := create()
+	path := "/api/v1/quotes/" + first.ID.String()
+
+	edit := httptest.NewRequest(http.MethodPatch, path, strings.NewReader(`{"quote":"Hope is a dream with open eyes."}`))
+	edit.Header.Set("Authorization", "Bearer secret")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, edit)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder, cached := create()
+	assert.Equal(t, "HIT", recorder.Header().Get("X-Cache"))
+	assert.Equal(t, first.ID, cached.ID)
+	assert.Equal(t, "Hope is a dream with open eyes.", cached.Quote, "the edited quote is served")
+
+	remove := httptest.NewRequest(http.MethodDelete, path, nil)
+	remove.Header.Set("Authorization", "Bearer secret")
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, remove)
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+
+	recorder, fresh := create()
+	assert.Equal(t, "MISS", recorder.Header().Get("X-Cache"))
+	assert.NotEqual(t, first.ID, fresh.ID, "the deleted quote is not served")
+}
@@ -0,0 +1,67 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Adeel56/quotebox/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadModelCatalog(t *testing.T) {
+	catalog, err := client.LoadModelCatalog()
+	require.NoError(t, err)
+	require.NotEmpty(t, catalog)
+
+	for _, info := range catalog {
+		assert.NotEmpty(t, info.ID)
+		assert.NotEmpty(t, info.Name, "model %s has no name", info.ID)
+	}
+}
+
+func TestNewModelAllowList(t *testing.T) {
+	t.Setenv("OPENROUTER_MODEL", "openai/gpt-4o-mini")
+	t.Setenv("OPENROUTER_ALLOWED_MODELS", "anthropic/claude-3.5-haiku, openai/gpt-4o-mini ,acme/unknown-1")
+
+	allowList, err := client.NewModelAllowList()
+	require.NoError(t, err)
+
+	assert.Equal(t, "openai/gpt-4o-mini", allowList.Default())
+	assert.Equal(t, []string{"openai/gpt-4o-mini", "anthropic/claude-3.5-haiku", "acme/unknown-1"}, allowList.IDs())
+	assert.True(t, allowList.Allowed("acme/unknown-1"))
+	assert.False(t, allowList.Allowed("openrouter/auto"))
+
+	models := allowList.Models()
+	assert.True(t, models[0].Default)
+	assert.Equal(t, "Claude 3.5 Haiku", models[1].Name)
+	assert.False(t, models[1].Default)
+	assert.Empty(t, models[2].Name)
+}
+
+func TestOpenRouterClient_RequestedModel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request client.ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Equal(t, "anthropic/claude-3.5-haiku", request.Model)
+
+		json.NewEncoder(w).Encode(client.ChatCompletionResponse{
+			Model:   "anthropic/claude-3.5-haiku-20241022",
+			Choices: []client.Choice{{Message: client.Message{Role: "assistant", Content: "Patience is the companion of wisdom."}}},
+		})
+	}))
+	defer server.Close()
+
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	t.Setenv("OPENROUTER_BASE_URL", server.URL)
+	t.Setenv("OPENROUTER_RESPONSE_FORMAT", client.ResponseFormatText)
+
+	result, err := client.NewOpenRouterClient().GenerateQuote(context.Background(), "calm", client.GenerateOptions{Model: "anthropic/claude-3.5-haiku"})
+	require.NoError(t, err)
+
+	// The model reported by OpenRouter is the one recorded
+	assert.Equal(t, "anthropic/claude-3.5-haiku-20241022", result.Model)
+}
@@ -0,0 +1,105 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Adeel56/quotebox/internal/client"
+	"github.com/Adeel56/quotebox/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubGenerator is a QuoteGenerator returning a fixed quote or error
+type stubGenerator struct {
+	name      string
+	text      string
+	err       error
+	available bool
+	calls     int
+}
+
+func (s *stubGenerator) Name() string {
+	return s.name
+}
+
+func (s *stubGenerator) GenerateQuote(ctx context.Context, tag string, opts client.GenerateOptions) (*client.GenerationResult, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &client.GenerationResult{Text: s.text, Source: s.name}, nil
+}
+
+func (s *stubGenerator) Available() bool {
+	return s.available
+}
+
+func resetProviderMetrics() {
+	metrics.QuoteProviderUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "quote_provider_up_test",
+	}, []string{"provider"})
+}
+
+func TestFallbackGenerator_FallsBackOnError(t *testing.T) {
+	resetProviderMetrics()
+
+	primary := &stubGenerator{name: "primary", err: errors.New("boom"), available: true}
+	secondary := &stubGenerator{name: "secondary", text: "Second time is the charm.", available: true}
+	chain := client.NewFallbackGenerator(primary, secondary)
+
+	result, err := chain.GenerateQuote(context.Background(), "joy", client.GenerateOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Second time is the charm.", result.Text)
+	assert.Equal(t, "secondary", result.Source)
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.QuoteProviderUp.WithLabelValues("primary")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.QuoteProviderUp.WithLabelValues("secondary")))
+}
+
+func TestFallbackGenerator_SkipsUnavailable(t *testing.T) {
+	resetProviderMetrics()
+
+	primary := &stubGenerator{name: "primary", text: "Never called.", available: false}
+	secondary := &stubGenerator{name: "secondary", text: "Served instead.", available: true}
+	chain := client.NewFallbackGenerator(primary, secondary)
+
+	result, err := chain.GenerateQuote(context.Background(), "joy", client.GenerateOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "secondary", result.Source)
+	assert.Equal(t, 0, primary.calls)
+}
+
+func TestFallbackGenerator_AllFail(t *testing.T) {
+	resetProviderMetrics()
+
+	chain := client.NewFallbackGenerator(
+		&stubGenerator{name: "primary", err: errors.New("boom"), available: true},
+		&stubGenerator{name: "secondary", available: false},
+	)
+
+	_, err := chain.GenerateQuote(context.Background(), "joy", client.GenerateOptions{})
+	assert.Error(t, err)
+	assert.ErrorIs(t, err, client.ErrProviderUnavailable)
+}
+
+func TestNewFallbackGenerator_KeepsStatusOfSharedProviders(t *testing.T) {
+	resetProviderMetrics()
+	metrics.Init()
+
+	shared := &stubGenerator{name: "shared", text: "Shared joy is double joy.", available: true}
+	primary := client.NewFallbackGenerator(shared)
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.QuoteProviderUp.WithLabelValues("shared")))
+
+	_, err := primary.GenerateQuote(context.Background(), "joy", client.GenerateOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.QuoteProviderUp.WithLabelValues("shared")))
+
+	// A second chain, e.g. the budget fallback, does not reset the status
+	client.NewFallbackGenerator(shared)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.QuoteProviderUp.WithLabelValues("shared")))
+}
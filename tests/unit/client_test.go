@@ -1,10 +1,14 @@
 package unit
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/Adeel56/quotebox/internal/client"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHTTPError_Error(t *testing.T) {
@@ -31,3 +35,106 @@ func TestNewOpenRouterClient(t *testing.T) {
 	assert.Equal(t, "https://test.example.com", c.BaseURL)
 	assert.NotNil(t, c.HTTPClient)
 }
+
+func TestOpenRouterClient_GenerateQuote(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "gen-1",
+			"model": "openai/gpt-4o-mini",
+			"choices": [{"message": {"role": "assistant", "content": "Joy is the echo of a grateful heart."}, "finish_reason": "stop"}],
+			"usage": {"prompt_tokens": 42, "completion_tokens": 9, "total_tokens": 51}
+		}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	t.Setenv("OPENROUTER_BASE_URL", server.URL)
+
+	c := client.NewOpenRouterClient()
+	result, err := c.GenerateQuote(context.Background(), "joy", client.GenerateOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Joy is the echo of a grateful heart.", result.Text)
+	assert.Equal(t, "openai/gpt-4o-mini", result.Model)
+	assert.Equal(t, client.ProviderOpenRouter, result.Source)
+	assert.Equal(t, 51, result.Usage.TotalTokens)
+}
+
+func TestNewGenerator(t *testing.T) {
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+
+	t.Setenv("QUOTE_PROVIDER", "")
+	assert.Equal(t, []string{client.ProviderOpenRouter}, client.ConfiguredProviders())
+	_, err := client.NewGenerator()
+	assert.NoError(t, err)
+
+	t.Setenv("QUOTE_PROVIDER", "OpenRouter, corpus")
+	assert.Equal(t, []string{client.ProviderOpenRouter, client.ProviderCorpus}, client.ConfiguredProviders())
+
+	t.Setenv("QUOTE_PROVIDER", "unknown")
+	_, err = client.NewGenerator()
+	assert.Error(t, err)
+
+	t.Setenv("QUOTE_PROVIDER", "corpus,corpus")
+	_, err = client.NewGenerator()
+	assert.Error(t, err)
+}
+
+func TestNewGenerators_ShareProviders(t *testing.T) {
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+
+	generators, err := client.NewGenerators([]string{client.ProviderOpenRouter, client.ProviderOllama}, []string{client.ProviderOllama, client.ProviderCorpus})
+	require.NoError(t, err)
+	require.Len(t, generators, 2)
+
+	main := generators[0].(*client.FallbackGenerator)
+	fallback := generators[1].(*client.FallbackGenerator)
+	require.Len(t, fallback.Providers, 2)
+	assert.Same(t, main.Providers[1], fallback.Providers[0], "both chains use the same provider and circuit breaker")
+	_, ok := main.Providers[1].(*client.BreakerGenerator)
+	assert.True(t, ok)
+}
+
+func TestOllamaClient_GenerateQuote(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/chat":
+			w.Write([]byte(`{"model": "llama3.2", "message": {"role": "assistant", "content": "Hope is a quiet lantern in the dark."}, "done": true, "prompt_eval_count": 30, "eval_count": 10}`))
+		case "/api/generate":
+			w.Write([]byte(`{"model": "llama3.2", "response": "Calm waters reflect the clearest sky.", "done": true}`))
+		case "/v1/chat/completions":
+			w.Write([]byte(`{"model": "local", "choices": [{"message": {"role": "assistant", "content": "Pride is a mirror; humility is a window."}}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		api      string
+		expected string
+	}{
+		{client.OllamaAPIChat, "Hope is a quiet lantern in the dark."},
+		{client.OllamaAPIGenerate, "Calm waters reflect the clearest sky."},
+		{client.OllamaAPIOpenAI, "Pride is a mirror; humility is a window."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.api, func(t *testing.T) {
+			t.Setenv("OLLAMA_BASE_URL", server.URL)
+			t.Setenv("OLLAMA_API", tt.api)
+
+			c := client.NewOllamaClient()
+			result, err := c.GenerateQuote(context.Background(), "hope", client.GenerateOptions{})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result.Text)
+			assert.Equal(t, client.ProviderOllama, result.Source)
+		})
+	}
+}
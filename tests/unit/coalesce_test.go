@@ -0,0 +1,337 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Adeel56/quotebox/internal/app/handlers"
+	"github.com/Adeel56/quotebox/internal/client"
+	"github.com/Adeel56/quotebox/internal/dedup"
+	"github.com/Adeel56/quotebox/internal/metrics"
+	"github.com/Adeel56/quotebox/internal/models"
+	"github.com/Adeel56/quotebox/internal/store"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gatedGenerator blocks every call until release is closed and returns
+// opts.Candidates numbered quotes, or err when set. With slowCancel, cancelled
+// calls also wait for release.
+type gatedGenerator struct {
+	release    chan struct{}
+	calls      int32
+	err        error
+	slowCancel bool
+}
+
+func (g *gatedGenerator) Name() string {
+	return "gated"
+}
+
+func (g *gatedGenerator) GenerateQuote(ctx context.Context, tag string, opts client.GenerateOptions) (*client.GenerationResult, error) {
+	atomic.AddInt32(&g.calls, 1)
+	if g.slowCancel {
+		<-g.release
+	}
+	select {
+	case <-g.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if g.err != nil {
+		return nil, g.err
+	}
+
+	result := &client.GenerationResult{Text: "quote 1", Usage: client.Usage{TotalTokens: 30}}
+	for i := 2; i <= opts.Candidates; i++ {
+		result.Alternatives = append(result.Alternatives, &client.GenerationResult{Text: fmt.Sprintf("quote %d", i)})
+	}
+	return result, nil
+}
+
+func resetCoalesceMetrics() {
+	metrics.QuoteCoalescedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "quote_coalesced_total_test",
+	})
+}
+
+// generateConcurrently starts n identical requests, waits until they all
+// joined the first one's call, then releases it
+func generateConcurrently(t *testing.T, generator *client.CoalescingGenerator, gate *gatedGenerator, n int) []*client.GenerationResult {
+	results := make([]*client.GenerationResult, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result, err := generator.GenerateQuote(context.Background(), "joy", client.GenerateOptions{Tone: "stoic"})
+			assert.NoError(t, err)
+			results[i] = result
+		}(i)
+	}
+
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(metrics.QuoteCoalescedTotal) == float64(n-1)
+	}, time.Second, time.Millisecond)
+	close(gate.release)
+	wg.Wait()
+	return results
+}
+
+func TestCoalesceConfigFromEnv(t *testing.T) {
+	config := client.CoalesceConfigFromEnv()
+	assert.False(t, config.Enabled)
+	assert.Equal(t, 1, config.Candidates)
+
+	t.Setenv("QUOTE_COALESCE", "true")
+	t.Setenv("QUOTE_COALESCE_CANDIDATES", "0")
+	config = client.CoalesceConfigFromEnv()
+	assert.True(t, config.Enabled)
+	assert.Equal(t, 1, config.Candidates)
+}
+
+func TestCoalescingGenerator_SharesOneCall(t *testing.T) {
+	resetCoalesceMetrics()
+	gate := &gatedGenerator{release: make(chan struct{})}
+	generator := client.WithCoalescing(gate, client.CoalesceConfig{Enabled: true, Candidates: 1})
+
+	results := generateConcurrently(t, generator, gate, 3)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&gate.calls))
+	owners := 0
+	for _, result := range results {
+		assert.Equal(t, "quote 1", result.Text)
+		if !result.Shared {
+			owners++
+			assert.Equal(t, 30, result.Usage.TotalTokens)
+		} else {
+			assert.Zero(t, result.Usage.TotalTokens)
+		}
+	}
+	assert.Equal(t, 1, owners, "exactly one request accounts for the call's usage")
+}
+
+func TestCoalescingGenerator_NewRequestsSkipCancelledCall(t *testing.T) {
+	resetCoalesceMetrics()
+	gate := &gatedGenerator{release: make(chan struct{}), slowCancel: true}
+	generator := client.WithCoalescing(gate, client.CoalesceConfig{Enabled: true})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error)
+	go func() {
+		_, err := generator.GenerateQuote(ctx, "joy", client.GenerateOptions{})
+		cancelled <- err
+	}()
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&gate.calls) == 1 }, time.Second, time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-cancelled, context.Canceled)
+
+	// The cancelled call is still running, but a new request must not join it
+	done := make(chan error)
+	go func() {
+		_, err := generator.GenerateQuote(context.Background(), "joy", client.GenerateOptions{})
+		done <- err
+	}()
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&gate.calls) == 2 }, time.Second, time.Millisecond)
+	close(gate.release)
+	assert.NoError(t, <-done)
+	assert.Zero(t, testutil.ToFloat64(metrics.QuoteCoalescedTotal))
+}
+
+func TestCoalescingGenerator_AccountsFailedCallOnce(t *testing.T) {
+	resetCoalesceMetrics()
+	rejected := &client.UsageError{Err: &client.RejectionError{Reason: client.RejectRefusal}, Usage: client.Usage{TotalTokens: 30}}
+	gate := &gatedGenerator{release: make(chan struct{}), err: rejected}
+	generator := client.WithCoalescing(gate, client.CoalesceConfig{Enabled: true})
+
+	errs := make([]error, 3)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = generator.GenerateQuote(context.Background(), "joy", client.GenerateOptions{})
+		}(i)
+	}
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(metrics.QuoteCoalescedTotal) == 2
+	}, time.Second, time.Millisecond)
+	close(gate.release)
+	wg.Wait()
+
+	spent := 0
+	for _, err := range errs {
+		var rejection *client.RejectionError
+		assert.True(t, errors.As(err, &rejection))
+		usage, _ := client.SpentUsage(err)
+		spent += usage.TotalTokens
+	}
+	assert.Equal(t, 30, spent, "the failed call's usage is returned to one request only")
+}
+
+func TestCoalescingGenerator_HandsOutCandidates(t *testing.T) {
+	resetCoalesceMetrics()
+	gate := &gatedGenerator{release: make(chan struct{})}
+	generator := client.WithCoalescing(gate, client.CoalesceConfig{Enabled: true, Candidates: 3})
+
+	results := generateConcurrently(t, generator, gate, 3)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&gate.calls))
+	texts := make(map[string]bool)
+	for _, result := range results {
+		texts[result.Text] = true
+		assert.Empty(t, result.Alternatives)
+		assert.False(t, result.Shared, "distinct candidates are not copies")
+	}
+	assert.Len(t, texts, 3, "every waiter gets a distinct candidate")
+}
+
+func TestCoalescingGenerator_SequentialRequestsAreNotShared(t *testing.T) {
+	resetCoalesceMetrics()
+	gate := &gatedGenerator{release: make(chan struct{})}
+	close(gate.release)
+	generator := client.WithCoalescing(gate, client.CoalesceConfig{Enabled: true})
+
+	for i := 0; i < 2; i++ {
+		result, err := generator.GenerateQuote(context.Background(), "joy", client.GenerateOptions{})
+		require.NoError(t, err)
+		assert.False(t, result.Shared)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&gate.calls))
+}
+
+func TestOpenRouterClient_MultipleCandidates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request client.ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Equal(t, 2, request.N)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"model": "openai/gpt-4o-mini",
+			"choices": [
+				{"message": {"role": "assistant", "content": "Joy is the echo of a grateful heart."}},
+				{"message": {"role": "assistant", "content": "Joy shared is joy doubled."}}
+			],
+			"usage": {"prompt_tokens": 42, "completion_tokens": 18, "total_tokens": 60}
+		}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	t.Setenv("OPENROUTER_BASE_URL", server.URL)
+
+	c := client.NewOpenRouterClient()
+	result, err := c.GenerateQuote(context.Background(), "joy", client.GenerateOptions{Candidates: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "Joy is the echo of a grateful heart.", result.Text)
+	assert.Equal(t, 60, result.Usage.TotalTokens)
+	require.Len(t, result.Alternatives, 1)
+	assert.Equal(t, "Joy shared is joy doubled.", result.Alternatives[0].Text)
+	assert.Equal(t, "openai/gpt-4o-mini", result.Alternatives[0].Model)
+	assert.Equal(t, result.PromptVersion, result.Alternatives[0].PromptVersion)
+}
+
+func TestValidatingGenerator_PromotesAcceptedCandidate(t *testing.T) {
+	provider := &stubResultGenerator{result: &client.GenerationResult{
+		Text:  "I'm sorry, I can't help with that.",
+		Usage: client.Usage{TotalTokens: 60},
+		Alternatives: []*client.GenerationResult{
+			{Text: "Joy shared is joy doubled."},
+			{Text: "Joy is the echo of a grateful heart."},
+		},
+	}}
+	generator := client.WithPipeline(provider, client.NewPipeline(client.PipelineConfig{MaxAttempts: 1}))
+
+	result, err := generator.GenerateQuote(context.Background(), "joy", client.GenerateOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "Joy shared is joy doubled.", result.Text)
+	assert.Equal(t, 60, result.Usage.TotalTokens)
+	require.Len(t, result.Alternatives, 1)
+	assert.Equal(t, "Joy is the echo of a grateful heart.", result.Alternatives[0].Text)
+}
+
+// stubResultGenerator returns the given result
+type stubResultGenerator struct {
+	result *client.GenerationResult
+}
+
+func (s *stubResultGenerator) Name() string {
+	return "stub"
+}
+
+func (s *stubResultGenerator) GenerateQuote(ctx context.Context, tag string, opts client.GenerateOptions) (*client.GenerationResult, error) {
+	return s.result, nil
+}
+
+func TestQuoteHandler_StoresSharedQuotesAsDuplicates(t *testing.T) {
+	quotes := store.NewMemoryStore()
+	original := models.Quote{Tag: "joy", QuoteText: "Joy shared is joy doubled.", TextHash: dedup.Hash("Joy shared is joy doubled.")}
+	require.NoError(t, quotes.Create(context.Background(), &original))
+
+	// A shared copy is stored with a reference even in regenerate mode
+	handler := handlers.NewQuoteHandler(&stubResultGenerator{result: &client.GenerationResult{
+		Text:   "Joy shared is joy doubled.",
+		Shared: true,
+	}}, quotes)
+	handler.Dedup = dedup.NewDetector(dedup.Config{Mode: dedup.ModeRegenerate, Threshold: 0.8, Window: 50, MaxAttempts: 2})
+	router := newQuoteRouter(handler)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/quote", strings.NewReader(`{"tag":"joy"}`)))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var response handlers.QuoteResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.NotEqual(t, original.ID, response.ID)
+	require.NotNil(t, response.DuplicateOf)
+	assert.Equal(t, original.ID, *response.DuplicateOf)
+}
+
+func TestQuoteHandler_StoresCoalescedCopiesAsDuplicates(t *testing.T) {
+	resetCoalesceMetrics()
+	quotes := store.NewMemoryStore()
+	gate := &gatedGenerator{release: make(chan struct{})}
+	handler := handlers.NewQuoteHandler(client.WithCoalescing(gate, client.CoalesceConfig{Enabled: true, Candidates: 1}), quotes)
+	handler.Dedup = dedup.NewDetector(dedup.Config{Mode: dedup.ModeReference, Threshold: 0.8, Window: 50})
+	router := newQuoteRouter(handler)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/quote", strings.NewReader(`{"tag":"joy"}`)))
+			assert.Equal(t, http.StatusOK, recorder.Code)
+		}()
+	}
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(metrics.QuoteCoalescedTotal) == 2
+	}, time.Second, time.Millisecond)
+	close(gate.release)
+	wg.Wait()
+
+	stored, err := quotes.List(context.Background(), store.QuoteFilter{Tag: "joy", Oldest: true})
+	require.NoError(t, err)
+	require.Len(t, stored, 3)
+	assert.Nil(t, stored[0].DuplicateOf)
+	for _, quote := range stored[1:] {
+		require.NotNil(t, quote.DuplicateOf)
+		assert.Equal(t, stored[0].ID, *quote.DuplicateOf)
+	}
+}
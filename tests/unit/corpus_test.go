@@ -0,0 +1,78 @@
+package unit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Adeel56/quotebox/internal/client"
+	"github.com/Adeel56/quotebox/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCorpusClient_CoversPresetTags(t *testing.T) {
+	c, err := client.NewCorpusClient()
+	require.NoError(t, err)
+
+	for _, tag := range models.ValidTags {
+		assert.Equal(t, tag, c.MatchTag(tag), "Tag %s has no corpus quotes", tag)
+	}
+}
+
+func TestCorpusClient_GenerateQuote(t *testing.T) {
+	c := client.NewCorpusClientFromEntries([]client.CorpusEntry{
+		{Text: "Hope is the thing with feathers that perches in the soul.", Author: "Emily Dickinson", Tags: []string{"hope"}},
+	})
+
+	result, err := c.GenerateQuote(context.Background(), "hope", client.GenerateOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Hope is the thing with feathers that perches in the soul.", result.Text)
+	require.NotNil(t, result.Author)
+	assert.Equal(t, "Emily Dickinson", *result.Author)
+	assert.Equal(t, "corpus", result.Source)
+}
+
+func TestCorpusClient_MatchTag(t *testing.T) {
+	c := client.NewCorpusClientFromEntries([]client.CorpusEntry{
+		{Text: "Joy is the simplest form of gratitude.", Tags: []string{"joy", "gratitude"}},
+		{Text: "Speak when you are angry and you will regret it.", Tags: []string{"anger"}},
+		{Text: "Hope is the thing with feathers.", Tags: []string{"hope"}},
+	})
+
+	tests := []struct {
+		tag      string
+		expected string
+	}{
+		{"Joy", "joy"},
+		{"joyful", "joy"},
+		{"angry", "anger"},
+		{"grateful", "gratitude"},
+		{"hopr", "hope"},
+		{"quantum", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			assert.Equal(t, tt.expected, c.MatchTag(tt.tag))
+		})
+	}
+}
+
+func TestParseCorpus(t *testing.T) {
+	csvData := []byte("text,author,tags\n\"Wisdom begins in wonder.\",Socrates,wonder|curiosity\n")
+	entries, err := client.ParseCorpus(csvData, client.CorpusFormatCSV)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "Socrates", entries[0].Author)
+	assert.Equal(t, []string{"wonder", "curiosity"}, entries[0].Tags)
+
+	yamlData := []byte("- text: Wisdom begins in wonder.\n  author: Socrates\n  tags: [wonder]\n")
+	entries, err = client.ParseCorpus(yamlData, client.CorpusFormatYAML)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "Wisdom begins in wonder.", entries[0].Text)
+
+	_, err = client.ParseCorpus(yamlData, "xml")
+	assert.Error(t, err)
+}
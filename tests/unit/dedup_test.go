@@ -0,0 +1,78 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/Adeel56/quotebox/internal/dedup"
+	"github.com/Adeel56/quotebox/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedupConfigFromEnv(t *testing.T) {
+	config := dedup.ConfigFromEnv()
+	assert.Equal(t, dedup.ModeReference, config.Mode)
+	assert.True(t, config.Enabled())
+
+	t.Setenv("QUOTE_DEDUP_MODE", "Regenerate")
+	t.Setenv("QUOTE_DEDUP_THRESHOLD", "1.5")
+	t.Setenv("QUOTE_DEDUP_WINDOW", "10")
+	config = dedup.ConfigFromEnv()
+	assert.Equal(t, dedup.ModeRegenerate, config.Mode)
+	assert.Equal(t, 0.8, config.Threshold)
+	assert.Equal(t, 10, config.Window)
+
+	t.Setenv("QUOTE_DEDUP_MODE", "off")
+	assert.False(t, dedup.ConfigFromEnv().Enabled())
+}
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "hope is a waking dream", dedup.Normalize(`  “Hope is a  waking dream!” `))
+	assert.Equal(t, dedup.Hash(`"Hope is a waking dream."`), dedup.Hash("hope is a waking dream"))
+	assert.NotEqual(t, dedup.Hash("Hope is a waking dream."), dedup.Hash("Hope is a dream."))
+}
+
+func TestSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, dedup.Similarity("Hope is a waking dream.", "hope is a waking dream"))
+	assert.Greater(t, dedup.Similarity(
+		"The only way to do great work is to love what you do.",
+		"The only way to do great work is to love the work you do.",
+	), 0.8)
+	assert.Less(t, dedup.Similarity("Hope is a waking dream.", "Courage is grace under pressure."), 0.3)
+}
+
+func TestDetector_Find(t *testing.T) {
+	detector := dedup.NewDetector(dedup.Config{Threshold: 0.8})
+	candidates := []dedup.Candidate{
+		{Text: "Courage is grace under pressure.", Hash: dedup.Hash("Courage is grace under pressure.")},
+		{Text: "The only way to do great work is to love what you do."},
+		{Text: "Hope is a waking dream.", Hash: dedup.Hash("Hope is a waking dream.")},
+	}
+
+	assert.Equal(t, 2, detector.Find(`"Hope is a waking dream!"`, candidates), "exact match after normalisation")
+	assert.Equal(t, 1, detector.Find("The only way to do great work is to love the work you do.", candidates), "near-duplicate")
+	assert.Equal(t, -1, detector.Find("Joy shared is joy doubled.", candidates))
+	assert.Equal(t, -1, detector.Find("Hope is a waking dream.", nil))
+}
+
+func TestRecordDuplicateCheck(t *testing.T) {
+	metrics.QuoteDuplicateChecksTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "quote_duplicate_checks_total_test",
+	}, []string{"tag"})
+	metrics.QuoteDuplicatesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "quote_duplicates_total_test",
+	}, []string{"tag"})
+	metrics.QuoteDuplicateRate = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "quote_duplicate_rate_test",
+	}, []string{"tag"})
+
+	metrics.RecordDuplicateCheck("dedup-test", true)
+	metrics.RecordDuplicateCheck("dedup-test", false)
+	metrics.RecordDuplicateCheck("dedup-test", false)
+	metrics.RecordDuplicateCheck("dedup-test", true)
+
+	assert.Equal(t, float64(4), testutil.ToFloat64(metrics.QuoteDuplicateChecksTotal.WithLabelValues("dedup-test")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.QuoteDuplicatesTotal.WithLabelValues("dedup-test")))
+	assert.Equal(t, 0.5, testutil.ToFloat64(metrics.QuoteDuplicateRate.WithLabelValues("dedup-test")))
+}
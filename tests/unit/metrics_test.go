@@ -38,19 +38,24 @@ func TestRecordQuoteError(t *testing.T) {
 	assert.Equal(t, float64(1), count)
 }
 
-func TestSetOpenRouterStatus(t *testing.T) {
+func TestSetProviderStatus(t *testing.T) {
 	// Reset metric before test
-	metrics.OpenRouterUp = prometheus.NewGauge(prometheus.GaugeOpts{
-		Name: "openrouter_up_test",
-	})
+	metrics.QuoteProviderUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "quote_provider_up_test",
+	}, []string{"provider"})
 
 	// Test setting to up
-	metrics.SetOpenRouterStatus(true)
-	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.OpenRouterUp))
+	metrics.SetProviderStatus("openrouter", true)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.QuoteProviderUp.WithLabelValues("openrouter")))
 
 	// Test setting to down
-	metrics.SetOpenRouterStatus(false)
-	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.OpenRouterUp))
+	metrics.SetProviderStatus("openrouter", false)
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.QuoteProviderUp.WithLabelValues("openrouter")))
+
+	// Providers are tracked independently
+	metrics.SetProviderStatus("corpus", true)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.QuoteProviderUp.WithLabelValues("corpus")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.QuoteProviderUp.WithLabelValues("openrouter")))
 }
 
 func TestRecordLatency(t *testing.T) {
@@ -78,3 +83,30 @@ func TestRecordLatency(t *testing.T) {
 	assert.Equal(t, uint64(3), histogram.GetSampleCount())
 	assert.Equal(t, float64(3.5), histogram.GetSampleSum())
 }
+
+func TestRecordQuoteModel(t *testing.T) {
+	metrics.QuotesByModel = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "quotes_by_model_test",
+	}, []string{"provider", "model"})
+
+	metrics.RecordQuoteModel("openrouter", "openai/gpt-4o-mini")
+	metrics.RecordQuoteModel("openrouter", "openai/gpt-4o-mini")
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.QuotesByModel.WithLabelValues("openrouter", "openai/gpt-4o-mini")))
+}
+
+func TestRecordUsage(t *testing.T) {
+	metrics.QuoteTokensTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "quote_tokens_total_test",
+	}, []string{"model", "type"})
+	metrics.QuoteCostUSDTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "quote_cost_usd_total_test",
+	}, []string{"model"})
+
+	metrics.RecordUsage("openai/gpt-4o-mini", 40, 10, 0.5)
+	metrics.RecordUsage("openai/gpt-4o-mini", 20, 5, 0.25)
+
+	assert.Equal(t, float64(60), testutil.ToFloat64(metrics.QuoteTokensTotal.WithLabelValues("openai/gpt-4o-mini", "prompt")))
+	assert.Equal(t, float64(15), testutil.ToFloat64(metrics.QuoteTokensTotal.WithLabelValues("openai/gpt-4o-mini", "completion")))
+	assert.Equal(t, 0.75, testutil.ToFloat64(metrics.QuoteCostUSDTotal.WithLabelValues("openai/gpt-4o-mini")))
+}
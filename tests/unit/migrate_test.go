@@ -0,0 +1,153 @@
+package unit
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Adeel56/quotebox/internal/db"
+	"github.com/Adeel56/quotebox/internal/store"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations_DialectsMatch(t *testing.T) {
+	postgres, err := db.LoadMigrations("postgres")
+	require.NoError(t, err)
+	sqlite, err := db.LoadMigrations("sqlite")
+	require.NoError(t, err)
+
+	require.NotEmpty(t, postgres)
+	require.Len(t, sqlite, len(postgres))
+	for i := range postgres {
+		assert.Equal(t, i+1, postgres[i].Version, "versions are consecutive")
+		assert.Equal(t, postgres[i].Version, sqlite[i].Version)
+		assert.Equal(t, postgres[i].Name, sqlite[i].Name)
+	}
+
+	_, err = db.LoadMigrations("mysql")
+	assert.Error(t, err)
+}
+
+func TestMigrator_UpDownStatus(t *testing.T) {
+	t.Setenv("DATABASE_URL", "sqlite://"+filepath.Join(t.TempDir(), "quotebox.db"))
+	database, err := db.Open()
+	require.NoError(t, err)
+	sqlDB, err := database.DB()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	migrator, err := db.NewMigrator(database)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	for _, status := range statuses {
+		assert.Nil(t, status.AppliedAt)
+	}
+
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Len(t, applied, len(statuses))
+	assert.True(t, database.Migrator().HasTable("quotes"))
+
+	applied, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied, "applied migrations are not run again")
+
+	reverted, err := migrator.Down(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, reverted, 1)
+	assert.Equal(t, statuses[len(statuses)-1].Version, reverted[0].Version)
+
+	statuses, err = migrator.Status(ctx)
+	require.NoError(t, err)
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.Nil(t, statuses[len(statuses)-1].AppliedAt)
+
+	_, err = migrator.Down(ctx, len(statuses))
+	require.NoError(t, err)
+	assert.False(t, database.Migrator().HasTable("quotes"))
+}
+
+// baselineQuote is the quotes table of the first release, created by AutoMigrate
+type baselineQuote struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key"`
+	Tag       string    `gorm:"type:varchar(50);not null;index"`
+	TagSource string    `gorm:"type:varchar(20);not null"`
+	QuoteText string    `gorm:"type:text;not null"`
+	Author    *string   `gorm:"type:varchar(255)"`
+	Source    string    `gorm:"type:varchar(50);not null"`
+	CreatedAt time.Time
+	LatencyMs int
+	ClientIP  string `gorm:"type:varchar(45)"`
+	UserAgent string `gorm:"type:text"`
+}
+
+func (baselineQuote) TableName() string {
+	return "quotes"
+}
+
+func TestMigrator_UpgradesBaselineSchema(t *testing.T) {
+	t.Setenv("DATABASE_URL", "sqlite://"+filepath.Join(t.TempDir(), "quotebox.db"))
+	database, err := db.Open()
+	require.NoError(t, err)
+	sqlDB, err := database.DB()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	require.NoError(t, database.AutoMigrate(&baselineQuote{}))
+	old := baselineQuote{ID: uuid.New(), Tag: "hope", TagSource: "preset", QuoteText: "Hope is a waking dream.", Source: "openrouter", CreatedAt: time.Now()}
+	require.NoError(t, database.Create(&old).Error)
+
+	migrator, err := db.NewMigrator(database)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	for _, column := range []string{"model", "text_hash", "duplicate_of", "deleted_at"} {
+		assert.True(t, database.Migrator().HasColumn("quotes", column), column)
+	}
+	quote, err := store.NewGormStore(database).Get(context.Background(), old.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Hope is a waking dream.", quote.QuoteText)
+}
+
+// softDeletedQuote stands for a quotes table that already has the column
+// migration 0003 adds, making that migration fail
+type softDeletedQuote struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key"`
+	Tag       string
+	DeletedAt *time.Time
+}
+
+func (softDeletedQuote) TableName() string {
+	return "quotes"
+}
+
+func TestMigrator_ReportsNothingAppliedWhenRolledBack(t *testing.T) {
+	t.Setenv("DATABASE_URL", "sqlite://"+filepath.Join(t.TempDir(), "quotebox.db"))
+	database, err := db.Open()
+	require.NoError(t, err)
+	sqlDB, err := database.DB()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	require.NoError(t, database.AutoMigrate(&softDeletedQuote{}))
+
+	migrator, err := db.NewMigrator(database)
+	require.NoError(t, err)
+	applied, err := migrator.Up(context.Background())
+	require.Error(t, err)
+	assert.Empty(t, applied, "the earlier migrations were rolled back with the failed one")
+
+	statuses, err := migrator.Status(context.Background())
+	require.NoError(t, err)
+	for _, status := range statuses {
+		assert.Nil(t, status.AppliedAt)
+	}
+	assert.False(t, database.Migrator().HasTable("budget_usages"))
+}
@@ -63,3 +63,17 @@ func TestValidTagsUniqueness(t *testing.T) {
 		tagMap[tag] = true
 	}
 }
+
+func TestIsValidOption(t *testing.T) {
+	assert.True(t, models.IsValidOption("stoic", models.ValidTones))
+	assert.True(t, models.IsValidOption("one_liner", models.ValidLengths))
+	assert.True(t, models.IsValidOption("kids", models.ValidAudiences))
+	assert.False(t, models.IsValidOption("sarcastic", models.ValidTones))
+	assert.False(t, models.IsValidOption("", models.ValidAudiences))
+}
+
+func TestIsValidLanguage(t *testing.T) {
+	assert.True(t, models.IsValidLanguage("fr"))
+	assert.False(t, models.IsValidLanguage("klingon"))
+	assert.False(t, models.IsValidLanguage("FR"))
+}
@@ -0,0 +1,160 @@
+package unit
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Adeel56/quotebox/internal/client"
+	"github.com/Adeel56/quotebox/internal/metrics"
+	"github.com/Adeel56/quotebox/internal/models"
+	"github.com/Adeel56/quotebox/internal/pool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func resetPoolMetrics() {
+	metrics.QuotePoolDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "quote_pool_depth_test",
+	}, []string{"tag"})
+	metrics.QuotePoolMissesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "quote_pool_misses_total_test",
+	}, []string{"tag"})
+	metrics.QuotePoolRefillLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name: "quote_pool_refill_latency_seconds_test",
+	})
+}
+
+// newTestPool returns a pool over the given tags whose generator answers immediately
+func newTestPool(tags ...string) (*pool.Pool, *gatedGenerator) {
+	gate := &gatedGenerator{release: make(chan struct{})}
+	close(gate.release)
+	return pool.New(gate, pool.Config{
+		Size:    2,
+		Workers: 2,
+		Rate:    1000,
+		MaxAge:  time.Hour,
+		Timeout: time.Second,
+		Tags:    tags,
+	}), gate
+}
+
+func TestPoolConfigFromEnv(t *testing.T) {
+	config := pool.ConfigFromEnv()
+	assert.False(t, config.Enabled())
+	assert.Equal(t, models.ValidTags, config.Tags)
+
+	t.Setenv("QUOTE_POOL_SIZE", "3")
+	t.Setenv("QUOTE_POOL_WORKERS", "0")
+	t.Setenv("QUOTE_POOL_RATE", "0.5")
+	t.Setenv("QUOTE_POOL_MAX_AGE", "10m")
+	config = pool.ConfigFromEnv()
+	assert.True(t, config.Enabled())
+	assert.Equal(t, 3, config.Size)
+	assert.Equal(t, 2, config.Workers)
+	assert.Equal(t, 0.5, config.Rate)
+	assert.Equal(t, 10*time.Minute, config.MaxAge)
+}
+
+func TestPool_FillsAndRefills(t *testing.T) {
+	resetPoolMetrics()
+	quotePool, gate := newTestPool("joy", "hope")
+	quotePool.Start()
+	defer quotePool.Stop()
+
+	require.Eventually(t, func() bool {
+		return quotePool.Depth("joy") == 2 && quotePool.Depth("hope") == 2
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&gate.calls))
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.QuotePoolDepth.WithLabelValues("joy")))
+
+	entry, ok := quotePool.Take("joy")
+	require.True(t, ok)
+	assert.Equal(t, "quote 1", entry.Result.Text)
+
+	// The taken quote is replaced
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&gate.calls) == 5 && quotePool.Depth("joy") == 2
+	}, time.Second, time.Millisecond)
+}
+
+func TestPool_Misses(t *testing.T) {
+	resetPoolMetrics()
+	quotePool, _ := newTestPool("joy")
+
+	_, ok := quotePool.Take("joy")
+	assert.False(t, ok)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.QuotePoolMissesTotal.WithLabelValues("joy")))
+
+	// Tags outside the pool are not misses
+	_, ok = quotePool.Take("custom tag")
+	assert.False(t, ok)
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.QuotePoolMissesTotal.WithLabelValues("custom tag")))
+}
+
+func TestPool_DiscardsStaleQuotes(t *testing.T) {
+	resetPoolMetrics()
+	quotePool, _ := newTestPool("joy")
+	now := time.Date(2026, 3, 14, 15, 0, 0, 0, time.UTC)
+	quotePool.SetClock(func() time.Time { return now })
+
+	quotePool.Start()
+	require.Eventually(t, func() bool {
+		return quotePool.Depth("joy") == 2
+	}, time.Second, time.Millisecond)
+	quotePool.Stop()
+
+	quotePool.SetClock(func() time.Time { return now.Add(2 * time.Hour) })
+	_, ok := quotePool.Take("joy")
+	assert.False(t, ok)
+	assert.Equal(t, 0, quotePool.Depth("joy"))
+}
+
+func TestPool_StopCancelsRefills(t *testing.T) {
+	resetPoolMetrics()
+	gate := &gatedGenerator{release: make(chan struct{})}
+	quotePool := pool.New(gate, pool.Config{Size: 1, Workers: 1, Rate: 1000, Timeout: time.Minute, Tags: []string{"joy"}})
+	quotePool.Start()
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&gate.calls) == 1
+	}, time.Second, time.Millisecond)
+
+	stopped := make(chan struct{})
+	go func() {
+		quotePool.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop did not cancel the blocked refill")
+	}
+	assert.Equal(t, 0, quotePool.Depth("joy"))
+}
+
+func TestPool_RefillsBypassCoalescing(t *testing.T) {
+	resetPoolMetrics()
+	gate := &gatedGenerator{release: make(chan struct{})}
+	generator := client.WithCoalescing(gate, client.CoalesceConfig{Enabled: true, Candidates: 1})
+	quotePool := pool.New(generator, pool.Config{Size: 4, Workers: 4, Rate: 1000, Timeout: time.Minute, Tags: []string{"joy"}})
+	quotePool.Start()
+	defer quotePool.Stop()
+
+	// Every worker makes its own upstream call instead of joining another's
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&gate.calls) == 4
+	}, time.Second, time.Millisecond)
+	close(gate.release)
+
+	require.Eventually(t, func() bool {
+		return quotePool.Depth("joy") == 4
+	}, time.Second, time.Millisecond)
+	for i := 0; i < 4; i++ {
+		entry, ok := quotePool.Take("joy")
+		require.True(t, ok)
+		assert.False(t, entry.Result.Shared)
+	}
+}
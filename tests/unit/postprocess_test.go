@@ -0,0 +1,177 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Adeel56/quotebox/internal/client"
+	"github.com/Adeel56/quotebox/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sequenceGenerator returns the given texts in order, one per call, each
+// with the given usage
+type sequenceGenerator struct {
+	texts []string
+	usage client.Usage
+	cost  float64
+	calls int
+}
+
+func (s *sequenceGenerator) Name() string {
+	return "sequence"
+}
+
+func (s *sequenceGenerator) GenerateQuote(ctx context.Context, tag string, opts client.GenerateOptions) (*client.GenerationResult, error) {
+	text := s.texts[s.calls]
+	s.calls++
+	return &client.GenerationResult{Text: text, Usage: s.usage, CostUSD: s.cost}, nil
+}
+
+func TestPipeline_Normalizes(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		quote  string
+		author string
+	}{
+		{"Plain", "Hope is a waking dream.", "Hope is a waking dream.", ""},
+		{"Wrapped in quotes", `"Hope is a waking dream."`, "Hope is a waking dream.", ""},
+		{"Curly quotes and bold", "**“Hope is a waking dream.”**", "Hope is a waking dream.", ""},
+		{"Preamble", "Here's a quote about hope:\n\n\"Hope is a waking dream.\"", "Hope is a waking dream.", ""},
+		{"Polite preamble", "Sure! Here is an inspirational quote: Hope is a waking dream.", "Hope is a waking dream.", ""},
+		{"Trailing author", `"Hope is a waking dream." — Aristotle`, "Hope is a waking dream.", "Aristotle"},
+		{"Author on own line", "> Hope is a waking dream.\n> - *Aristotle*", "Hope is a waking dream.", "Aristotle"},
+		{"Hyphen inside quote", "Be yourself - everyone else is already taken.", "Be yourself - everyone else is already taken.", ""},
+	}
+
+	pipeline := client.NewPipeline(client.PipelineConfig{MinLength: 10, MaxLength: 300, MaxSentences: 3})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &client.GenerationResult{Text: tt.input}
+			require.NoError(t, pipeline.Process(result))
+
+			assert.Equal(t, tt.quote, result.Text)
+			if tt.author == "" {
+				assert.Nil(t, result.Author)
+			} else {
+				require.NotNil(t, result.Author)
+				assert.Equal(t, tt.author, *result.Author)
+			}
+		})
+	}
+}
+
+func TestPipeline_KeepsProviderAuthor(t *testing.T) {
+	pipeline := client.NewPipeline(client.PipelineConfig{})
+	author := "Aristotle"
+	result := &client.GenerationResult{Text: "Hope is a waking dream. — Unknown", Author: &author}
+
+	require.NoError(t, pipeline.Process(result))
+	assert.Equal(t, "Hope is a waking dream.", result.Text)
+	assert.Equal(t, "Aristotle", *result.Author)
+}
+
+func TestPipeline_Rejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		reason string
+	}{
+		{"Empty", `""`, client.RejectEmpty},
+		{"Too short", "Be kind.", client.RejectTooShort},
+		{"Too long", "Patience is bitter, but its fruit is sweet, and the longest road is walked one step at a time.", client.RejectTooLong},
+		{"Too many sentences", "Rise. Fall. Rise again. Repeat.", client.RejectTooManySentences},
+		{"Refusal", "I can’t help with that request.", client.RejectRefusal},
+		{"AI disclaimer", "As an AI, I do not have personal opinions.", client.RejectRefusal},
+	}
+
+	pipeline := client.NewPipeline(client.PipelineConfig{MinLength: 10, MaxLength: 80, MaxSentences: 3})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := pipeline.Process(&client.GenerationResult{Text: tt.input})
+
+			var rejection *client.RejectionError
+			require.True(t, errors.As(err, &rejection), "expected a rejection, got %v", err)
+			assert.Equal(t, tt.reason, rejection.Reason)
+		})
+	}
+}
+
+func TestValidatingGenerator_RetriesRejectedQuotes(t *testing.T) {
+	before := testutil.ToFloat64(metrics.QuoteRejectionsTotal.WithLabelValues("sequence", client.RejectRefusal))
+
+	provider := &sequenceGenerator{texts: []string{
+		"I'm sorry, but I cannot do that.",
+		"Here's a quote: \"Hope is a waking dream.\" — Aristotle",
+	}}
+	generator := client.WithPipeline(provider, client.NewPipeline(client.PipelineConfig{MinLength: 10, MaxAttempts: 3}))
+
+	result, err := generator.GenerateQuote(context.Background(), "hope", client.GenerateOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, provider.calls)
+	assert.Equal(t, "Hope is a waking dream.", result.Text)
+	require.NotNil(t, result.Author)
+	assert.Equal(t, "Aristotle", *result.Author)
+	assert.Equal(t, before+1, testutil.ToFloat64(metrics.QuoteRejectionsTotal.WithLabelValues("sequence", client.RejectRefusal)))
+}
+
+func TestValidatingGenerator_GivesUpAfterMaxAttempts(t *testing.T) {
+	provider := &sequenceGenerator{texts: []string{"Short.", "Tiny.", "Brief."}}
+	generator := client.WithPipeline(provider, client.NewPipeline(client.PipelineConfig{MinLength: 10, MaxAttempts: 2}))
+
+	_, err := generator.GenerateQuote(context.Background(), "hope", client.GenerateOptions{})
+
+	var rejection *client.RejectionError
+	require.True(t, errors.As(err, &rejection))
+	assert.Equal(t, client.RejectTooShort, rejection.Reason)
+	assert.Equal(t, 2, provider.calls)
+}
+
+func TestValidatingGenerator_AccountsRejectedAttempts(t *testing.T) {
+	provider := &sequenceGenerator{
+		texts: []string{"Short.", "Hope is a waking dream."},
+		usage: client.Usage{PromptTokens: 20, CompletionTokens: 10, TotalTokens: 30},
+		cost:  0.25,
+	}
+	generator := client.WithPipeline(provider, client.NewPipeline(client.PipelineConfig{MinLength: 10, MaxAttempts: 3}))
+
+	result, err := generator.GenerateQuote(context.Background(), "hope", client.GenerateOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, client.Usage{PromptTokens: 40, CompletionTokens: 20, TotalTokens: 60}, result.Usage)
+	assert.Equal(t, 0.5, result.CostUSD)
+
+	provider = &sequenceGenerator{texts: []string{"Short.", "Tiny."}, usage: client.Usage{TotalTokens: 30}, cost: 0.25}
+	generator = client.WithPipeline(provider, client.NewPipeline(client.PipelineConfig{MinLength: 10, MaxAttempts: 2}))
+
+	_, err = generator.GenerateQuote(context.Background(), "hope", client.GenerateOptions{})
+	var rejection *client.RejectionError
+	require.True(t, errors.As(err, &rejection))
+	usage, cost := client.SpentUsage(err)
+	assert.Equal(t, 60, usage.TotalTokens)
+	assert.Equal(t, 0.5, cost)
+}
+
+func TestFallbackGenerator_AccountsFailedProviders(t *testing.T) {
+	resetProviderMetrics()
+	pipeline := client.NewPipeline(client.PipelineConfig{MinLength: 10, MaxAttempts: 1})
+	rejected := func() client.QuoteGenerator {
+		return client.WithPipeline(&sequenceGenerator{texts: []string{"Short."}, usage: client.Usage{TotalTokens: 30}, cost: 0.25}, pipeline)
+	}
+	accepted := &stubResultGenerator{result: &client.GenerationResult{Text: "Hope is a waking dream.", Usage: client.Usage{TotalTokens: 12}}}
+
+	result, err := client.NewFallbackGenerator(rejected(), accepted).GenerateQuote(context.Background(), "hope", client.GenerateOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 42, result.Usage.TotalTokens)
+	assert.Equal(t, 0.25, result.CostUSD)
+
+	_, err = client.NewFallbackGenerator(rejected(), rejected()).GenerateQuote(context.Background(), "hope", client.GenerateOptions{})
+	require.Error(t, err)
+	usage, cost := client.SpentUsage(err)
+	assert.Equal(t, 60, usage.TotalTokens)
+	assert.Equal(t, 0.5, cost)
+}
@@ -0,0 +1,84 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Adeel56/quotebox/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriceTable_Cost(t *testing.T) {
+	table, err := client.ParsePriceTable([]byte(`
+acme/small:
+  prompt: 1.0
+  completion: 2.0
+acme/small-v2:
+  prompt: 3.0
+  completion: 4.0
+`))
+	require.NoError(t, err)
+
+	usage := client.Usage{PromptTokens: 1000, CompletionTokens: 500}
+	assert.InDelta(t, 0.002, table.Cost("acme/small", usage), 1e-12)
+
+	// Dated snapshots use the price of the longest matching prefix
+	assert.InDelta(t, 0.005, table.Cost("acme/small-v2-20250101", usage), 1e-12)
+
+	// Unpriced models are free
+	assert.Equal(t, float64(0), table.Cost("llama3.2", usage))
+}
+
+func TestParsePriceTable_Invalid(t *testing.T) {
+	_, err := client.ParsePriceTable([]byte("acme/small:\n  prompt: -1\n"))
+	assert.Error(t, err)
+
+	_, err = client.ParsePriceTable([]byte("- not a map"))
+	assert.Error(t, err)
+}
+
+func TestEmbeddedPrices(t *testing.T) {
+	price, ok := client.EmbeddedPrices().Price("openai/gpt-4o-mini")
+	require.True(t, ok)
+	assert.Greater(t, price.Completion, price.Prompt)
+}
+
+func TestOpenRouterClient_Cost(t *testing.T) {
+	var reportedCost *float64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request client.ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		require.NotNil(t, request.Usage)
+		assert.True(t, request.Usage.Include)
+
+		json.NewEncoder(w).Encode(client.ChatCompletionResponse{
+			Model:   "openai/gpt-4o-mini",
+			Choices: []client.Choice{{Message: client.Message{Role: "assistant", Content: "Small steps still move you forward."}}},
+			Usage:   &client.Usage{PromptTokens: 40, CompletionTokens: 10, TotalTokens: 50, Cost: reportedCost},
+		})
+	}))
+	defer server.Close()
+
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	t.Setenv("OPENROUTER_BASE_URL", server.URL)
+	t.Setenv("OPENROUTER_RESPONSE_FORMAT", client.ResponseFormatText)
+	c := client.NewOpenRouterClient()
+
+	// Without a reported cost the price table is used
+	result, err := c.GenerateQuote(context.Background(), "resilience", client.GenerateOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 40, result.Usage.PromptTokens)
+	assert.Equal(t, 10, result.Usage.CompletionTokens)
+	assert.InDelta(t, (40*0.15+10*0.60)/1e6, result.CostUSD, 1e-12)
+
+	// OpenRouter's own figure wins when present
+	cost := 0.0042
+	reportedCost = &cost
+	result, err = c.GenerateQuote(context.Background(), "resilience", client.GenerateOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 0.0042, result.CostUSD)
+}
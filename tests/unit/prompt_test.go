@@ -0,0 +1,179 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Adeel56/quotebox/internal/app/handlers"
+	"github.com/Adeel56/quotebox/internal/client"
+	"github.com/Adeel56/quotebox/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedPrompts_SelectsVersion(t *testing.T) {
+	prompts := client.EmbeddedPrompts()
+
+	assert.Equal(t, "default-v1", prompts.Version("hope", "preset"))
+	assert.Equal(t, "playful-v1", prompts.Version("humor", "preset"))
+	assert.Equal(t, "custom-v1", prompts.Version("rainy mondays", "custom"))
+}
+
+func TestEmbeddedPrompts_Render(t *testing.T) {
+	prompts := client.EmbeddedPrompts()
+
+	prompt, err := prompts.Render(client.PromptData{Tag: "hope", TagSource: "preset"})
+	require.NoError(t, err)
+	assert.Equal(t, "default-v1", prompt.Version)
+	assert.Equal(t, "You are a wise philosopher who creates short, meaningful quotes. Always respond with only the quote text, nothing else.", prompt.System)
+	assert.Contains(t, prompt.User, "inspirational quote about hope.")
+
+	prompt, err = prompts.Render(client.PromptData{Tag: "hope", TagSource: "preset", Structured: true})
+	require.NoError(t, err)
+	assert.Contains(t, prompt.System, "single JSON object")
+	assert.NotContains(t, prompt.User, "Only return the quote text")
+}
+
+func TestLoadPromptRegistry(t *testing.T) {
+	files := fstest.MapFS{
+		"manifest.yaml": {Data: []byte("default: plain-v2\ntags:\n  joy: plain-v1\n")},
+		"_shared.tmpl":  {Data: []byte(`{{define "topic"}}about {{.Tag}}{{end}}`)},
+		"plain-v1.tmpl": {Data: []byte(`{{define "system"}}v1{{end}}{{define "user"}}Quote {{template "topic" .}}{{end}}`)},
+		"plain-v2.tmpl": {Data: []byte(`{{define "system"}}v2{{end}}{{define "user"}}A quote {{template "topic" .}}{{end}}`)},
+	}
+
+	registry, err := client.LoadPromptRegistry(files)
+	require.NoError(t, err)
+
+	prompt, err := registry.Render(client.PromptData{Tag: "joy"})
+	require.NoError(t, err)
+	assert.Equal(t, "plain-v1", prompt.Version)
+	assert.Equal(t, "Quote about joy", prompt.User)
+
+	prompt, err = registry.Render(client.PromptData{Tag: "calm"})
+	require.NoError(t, err)
+	assert.Equal(t, "plain-v2", prompt.Version)
+	assert.Equal(t, "v2", prompt.System)
+}
+
+func TestLoadPromptRegistry_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+	}{
+		{"Missing manifest", fstest.MapFS{
+			"plain-v1.tmpl": {Data: []byte(`{{define "system"}}s{{end}}{{define "user"}}u{{end}}`)},
+		}},
+		{"Unknown version", fstest.MapFS{
+			"manifest.yaml": {Data: []byte("default: plain-v9\n")},
+			"plain-v1.tmpl": {Data: []byte(`{{define "system"}}s{{end}}{{define "user"}}u{{end}}`)},
+		}},
+		{"Missing user template", fstest.MapFS{
+			"manifest.yaml": {Data: []byte("default: plain-v1\n")},
+			"plain-v1.tmpl": {Data: []byte(`{{define "system"}}s{{end}}`)},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.LoadPromptRegistry(tt.files)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNewPromptRegistry_PinnedVersion(t *testing.T) {
+	t.Setenv("QUOTE_PROMPT_VERSION", "custom-v1")
+	registry, err := client.NewPromptRegistry()
+	require.NoError(t, err)
+	assert.Equal(t, "custom-v1", registry.Version("hope", "preset"))
+
+	// The bundled registry is left untouched
+	assert.Equal(t, "default-v1", client.EmbeddedPrompts().Version("hope", "preset"))
+
+	t.Setenv("QUOTE_PROMPT_VERSION", "missing-v1")
+	_, err = client.NewPromptRegistry()
+	assert.Error(t, err)
+}
+
+func TestOpenRouterClient_RecordsPromptVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request client.ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Contains(t, request.Messages[1].Content, "light-hearted")
+		assert.Contains(t, request.Messages[1].Content, "Write the quote in French.")
+
+		json.NewEncoder(w).Encode(client.ChatCompletionResponse{
+			Choices: []client.Choice{{Message: client.Message{Role: "assistant", Content: "Laughter is the shortest distance between two people."}}},
+		})
+	}))
+	defer server.Close()
+
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	t.Setenv("OPENROUTER_BASE_URL", server.URL)
+	t.Setenv("OPENROUTER_RESPONSE_FORMAT", client.ResponseFormatText)
+
+	result, err := client.NewOpenRouterClient().GenerateQuote(context.Background(), "humor", client.GenerateOptions{Language: "fr"})
+	require.NoError(t, err)
+	assert.Equal(t, "playful-v1", result.PromptVersion)
+
+	// The requested language is kept when the model does not report one
+	require.NotNil(t, result.Language)
+	assert.Equal(t, "fr", *result.Language)
+}
+
+func TestQuoteHandler_StoresHonouredLanguage(t *testing.T) {
+	canadianFrench, french, english := "fr-CA", "fr", "en"
+	for _, tc := range []struct {
+		name     string
+		reported *string
+		want     *string
+	}{
+		{"Honoured with a region", &canadianFrench, &french},
+		{"Answered in another language", &english, &english},
+		{"Fallback without a language", nil, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			quotes := store.NewMemoryStore()
+			handler := handlers.NewQuoteHandler(&stubResultGenerator{result: &client.GenerationResult{
+				Text:     "Hope is a waking dream.",
+				Language: tc.reported,
+			}}, quotes)
+			router := newQuoteRouter(handler)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/quote", strings.NewReader(`{"tag":"hope","language":"fr"}`)))
+			require.Equal(t, http.StatusOK, recorder.Code)
+
+			stored, err := quotes.List(context.Background(), store.QuoteFilter{})
+			require.NoError(t, err)
+			require.Len(t, stored, 1)
+			assert.Equal(t, tc.want, stored[0].Language)
+		})
+	}
+}
+
+func TestEmbeddedPrompts_RenderOptions(t *testing.T) {
+	prompts := client.EmbeddedPrompts()
+
+	prompt, err := prompts.Render(client.PromptData{
+		Tag:       "hope",
+		TagSource: "preset",
+		Tone:      "stoic",
+		Length:    "one_liner",
+		Audience:  "kids",
+		Language:  "French",
+	})
+	require.NoError(t, err)
+
+	assert.Contains(t, prompt.User, "should be a single short sentence")
+	assert.NotContains(t, prompt.User, "1-2 sentences")
+	assert.Contains(t, prompt.User, "Use a stoic tone.")
+	assert.Contains(t, prompt.User, "for children")
+	assert.Contains(t, prompt.User, "Write the quote in French.")
+}
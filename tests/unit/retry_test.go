@@ -0,0 +1,160 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Adeel56/quotebox/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := client.RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    300 * time.Millisecond,
+	}
+
+	for i := 0; i < 50; i++ {
+		assert.LessOrEqual(t, policy.Backoff(1), 100*time.Millisecond)
+		assert.LessOrEqual(t, policy.Backoff(2), 200*time.Millisecond)
+		assert.LessOrEqual(t, policy.Backoff(10), 300*time.Millisecond)
+		assert.GreaterOrEqual(t, policy.Backoff(3), time.Duration(0))
+	}
+}
+
+func TestRetryPolicy_DelayPrefersRetryAfter(t *testing.T) {
+	policy := client.RetryPolicy{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	err := &client.HTTPError{StatusCode: 429, RetryAfter: 2 * time.Second}
+
+	assert.Equal(t, 2*time.Second, policy.Delay(1, err))
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	header := http.Header{}
+	header.Set("Retry-After", "3")
+	assert.Equal(t, 3*time.Second, client.ParseRetryAfter(header, now))
+
+	header = http.Header{}
+	header.Set("Retry-After", now.Add(5*time.Second).Format(http.TimeFormat))
+	assert.Equal(t, 5*time.Second, client.ParseRetryAfter(header, now))
+
+	header = http.Header{}
+	header.Set("X-RateLimit-Remaining", "0")
+	header.Set("X-RateLimit-Reset", strconv.FormatInt(now.Add(1500*time.Millisecond).UnixMilli(), 10))
+	assert.Equal(t, 1500*time.Millisecond, client.ParseRetryAfter(header, now))
+
+	header.Set("X-RateLimit-Remaining", "10")
+	assert.Equal(t, time.Duration(0), client.ParseRetryAfter(header, now))
+}
+
+func TestOpenRouterClient_RetriesTransientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			// Drop the connection to simulate a network error
+			conn, _, err := w.(http.Hijacker).Hijack()
+			require.NoError(t, err)
+			conn.Close()
+		default:
+			w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "Third time pays for all."}}]}`))
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	t.Setenv("OPENROUTER_BASE_URL", server.URL)
+	t.Setenv("OPENROUTER_RETRY_MAX_ATTEMPTS", "3")
+	t.Setenv("OPENROUTER_RETRY_BASE_DELAY", "1ms")
+
+	c := client.NewOpenRouterClient()
+	result, err := c.GenerateQuote(context.Background(), "joy", client.GenerateOptions{})
+
+	require.NoError(t, err)
+	assert.Equal(t, "Third time pays for all.", result.Text)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestOpenRouterClient_DoesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	t.Setenv("OPENROUTER_BASE_URL", server.URL)
+	t.Setenv("OPENROUTER_RETRY_BASE_DELAY", "1ms")
+
+	c := client.NewOpenRouterClient()
+	_, err := c.GenerateQuote(context.Background(), "joy", client.GenerateOptions{})
+
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestOpenRouterClient_StopsRetryingWhenCancelled(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	t.Setenv("OPENROUTER_BASE_URL", server.URL)
+	t.Setenv("OPENROUTER_RETRY_BUDGET", "0")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	c := client.NewOpenRouterClient()
+	_, err := c.GenerateQuote(ctx, "joy", client.GenerateOptions{})
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestOpenRouterClient_RetriesAttemptTimeouts(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			// Outlast the client timeout on the first attempt only
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "Patience is bitter, but its fruit is sweet."}}]}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	t.Setenv("OPENROUTER_BASE_URL", server.URL)
+	t.Setenv("OPENROUTER_RETRY_BASE_DELAY", "1ms")
+
+	c := client.NewOpenRouterClient()
+	c.HTTPClient.Timeout = 50 * time.Millisecond
+	result, err := c.GenerateQuote(context.Background(), "patience", client.GenerateOptions{})
+
+	require.NoError(t, err)
+	assert.Equal(t, "Patience is bitter, but its fruit is sweet.", result.Text)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,342 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Adeel56/quotebox/internal/app/handlers"
+	"github.com/Adeel56/quotebox/internal/client"
+	"github.com/Adeel56/quotebox/internal/db"
+	"github.com/Adeel56/quotebox/internal/models"
+	"github.com/Adeel56/quotebox/internal/store"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_CreateGetDelete(t *testing.T) {
+	ctx := context.Background()
+	quotes := store.NewMemoryStore()
+
+	quote := models.Quote{Tag: "hope", QuoteText: "Hope is a waking dream."}
+	require.NoError(t, quotes.Create(ctx, &quote))
+	assert.NotEqual(t, uuid.Nil, quote.ID)
+	assert.False(t, quote.CreatedAt.IsZero())
+
+	stored, err := quotes.Get(ctx, quote.ID)
+	require.NoError(t, err)
+	assert.Equal(t, quote.QuoteText, stored.QuoteText)
+
+	author := "Aristotle"
+	stored.QuoteText = "Hope is a dream of the waking."
+	stored.Author = &author
+	stored.Model = "ignored"
+	require.NoError(t, quotes.Update(ctx, stored))
+	stored, err = quotes.Get(ctx, quote.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Hope is a dream of the waking.", stored.QuoteText)
+	assert.Equal(t, "Aristotle", *stored.Author)
+	assert.Empty(t, stored.Model, "only editable fields are saved")
+
+	require.NoError(t, quotes.Delete(ctx, quote.ID))
+	_, err = quotes.Get(ctx, quote.ID)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	assert.ErrorIs(t, quotes.Delete(ctx, quote.ID), store.ErrNotFound)
+	assert.ErrorIs(t, quotes.Update(ctx, stored), store.ErrNotFound)
+	list, err := quotes.List(ctx, store.QuoteFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	restored, err := quotes.Restore(ctx, quote.ID)
+	require.NoError(t, err)
+	assert.Equal(t, quote.ID, restored.ID)
+	_, err = quotes.Get(ctx, quote.ID)
+	assert.NoError(t, err)
+
+	_, err = quotes.Restore(ctx, uuid.New())
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
+
+func TestMemoryStore_List(t *testing.T) {
+	ctx := context.Background()
+	quotes := store.NewMemoryStore()
+	now := time.Now()
+	for i, quote := range []models.Quote{
+		{Tag: "hope", QuoteText: "First", Tone: "stoic"},
+		{Tag: "hope", QuoteText: "Second"},
+		{Tag: "joy", QuoteText: "Third", Model: "openai/gpt-4o-mini"},
+	} {
+		quote.CreatedAt = now.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, quotes.Create(ctx, &quote))
+	}
+
+	texts := func(filter store.QuoteFilter) []string {
+		list, err := quotes.List(ctx, filter)
+		require.NoError(t, err)
+		var texts []string
+		for _, quote := range list {
+			texts = append(texts, quote.QuoteText)
+		}
+		return texts
+	}
+
+	assert.Equal(t, []string{"Third", "Second", "First"}, texts(store.QuoteFilter{}))
+	assert.Equal(t, []string{"First", "Second"}, texts(store.QuoteFilter{Tag: "hope", Oldest: true}))
+	assert.Equal(t, []string{"Third"}, texts(store.QuoteFilter{Limit: 1}))
+	assert.Equal(t, []string{"First"}, texts(store.QuoteFilter{Tone: "stoic"}))
+	assert.Equal(t, []string{"Second"}, texts(store.QuoteFilter{Tag: "hope", MatchEmpty: true}))
+	assert.Equal(t, []string{"Third"}, texts(store.QuoteFilter{Model: "openai/gpt-4o-mini"}))
+}
+
+func TestMemoryStore_Stats(t *testing.T) {
+	ctx := context.Background()
+	quotes := store.NewMemoryStore()
+	day := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, quote := range []models.Quote{
+		{Tag: "hope", Model: "a", TotalTokens: 10, CostUSD: 0.1, CreatedAt: day},
+		{Tag: "hope", Model: "a", TotalTokens: 20, CostUSD: 0.2, CreatedAt: day.Add(time.Hour)},
+		{Tag: "joy", Model: "b", TotalTokens: 5, CostUSD: 0.05, CreatedAt: day.AddDate(0, 0, 1)},
+		{Tag: "joy", Model: "b", TotalTokens: 99, CreatedAt: day.AddDate(0, 0, 5)},
+	} {
+		quote := quote
+		require.NoError(t, quotes.Create(ctx, &quote))
+	}
+
+	stats, err := quotes.Stats(ctx, store.StatsQuery{
+		From:    day.Truncate(24 * time.Hour),
+		To:      day.Truncate(24*time.Hour).AddDate(0, 0, 2),
+		GroupBy: []string{store.GroupDay, store.GroupTag},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stats.Totals.Quotes)
+	assert.Equal(t, int64(35), stats.Totals.TotalTokens)
+	assert.InDelta(t, 0.35, stats.Totals.CostUSD, 1e-9)
+	require.Len(t, stats.Rows, 2)
+	assert.Equal(t, "2026-03-01", stats.Rows[0].Day)
+	assert.Equal(t, "hope", stats.Rows[0].Tag)
+	assert.Equal(t, int64(2), stats.Rows[0].Quotes)
+	assert.Equal(t, int64(30), stats.Rows[0].TotalTokens)
+	assert.Equal(t, "2026-03-02", stats.Rows[1].Day)
+	assert.Equal(t, "joy", stats.Rows[1].Tag)
+
+	_, err = quotes.Stats(ctx, store.StatsQuery{GroupBy: []string{"week"}})
+	assert.Error(t, err)
+}
+
+func TestGormStore_SQLite(t *testing.T) {
+	t.Setenv("DATABASE_URL", "sqlite://"+filepath.Join(t.TempDir(), "quotebox.db"))
+	database, err := db.Connect()
+	require.NoError(t, err)
+	quotes := store.NewGormStore(database)
+	defer quotes.Close()
+
+	ctx := context.Background()
+	require.NoError(t, quotes.Ping(ctx))
+
+	day := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	first := models.Quote{Tag: "hope", TagSource: "preset", QuoteText: "First", Source: "stub", Tone: "stoic", TotalTokens: 10, CreatedAt: day}
+	second := models.Quote{Tag: "hope", TagSource: "preset", QuoteText: "Second", Source: "stub", TotalTokens: 5, CreatedAt: day.AddDate(0, 0, 1)}
+	require.NoError(t, quotes.Create(ctx, &first))
+	require.NoError(t, quotes.Create(ctx, &second))
+
+	stored, err := quotes.Get(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "First", stored.QuoteText)
+
+	list, err := quotes.List(ctx, store.QuoteFilter{Tag: "hope", MatchEmpty: true})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, second.ID, list[0].ID)
+
+	stats, err := quotes.Stats(ctx, store.StatsQuery{From: day.AddDate(0, 0, -1), To: day.AddDate(0, 0, 2), GroupBy: []string{store.GroupDay}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(15), stats.Totals.TotalTokens)
+	require.Len(t, stats.Rows, 2)
+	assert.Equal(t, "2026-03-01", stats.Rows[0].Day)
+	assert.Equal(t, "2026-03-02", stats.Rows[1].Day)
+
+	author := "Anonymous"
+	stored.QuoteText = "First, edited"
+	stored.Author = &author
+	require.NoError(t, quotes.Update(ctx, stored))
+	stored, err = quotes.Get(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "First, edited", stored.QuoteText)
+	assert.Equal(t, "Anonymous", *stored.Author)
+
+	require.NoError(t, quotes.Delete(ctx, first.ID))
+	_, err = quotes.Get(ctx, first.ID)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	assert.ErrorIs(t, quotes.Delete(ctx, first.ID), store.ErrNotFound)
+
+	stats, err = quotes.Stats(ctx, store.StatsQuery{From: day.AddDate(0, 0, -1), To: day.AddDate(0, 0, 2)})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.Totals.Quotes, "deleted quotes still count as usage")
+
+	restored, err := quotes.Restore(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "First, edited", restored.QuoteText)
+	_, err = quotes.Restore(ctx, uuid.New())
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
+
+// newQuoteRouter serves the quote handler's routes from a memory store
+func newQuoteRouter(handler *handlers.QuoteHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/v1/quote", handler.CreateQuote)
+	router.GET("/api/v1/quotes", handler.GetQuotes)
+	router.GET("/api/v1/quotes/:id", handler.GetQuote)
+	router.PATCH("/api/v1/quotes/:id", handlers.RequireAdmin("secret"), handler.UpdateQuote)
+	router.DELETE("/api/v1/quotes/:id", handlers.RequireAdmin("secret"), handler.DeleteQuote)
+	router.POST("/api/v1/quotes/:id/restore", handlers.RequireAdmin("secret"), handler.RestoreQuote)
+	router.GET("/api/v1/usage", handler.GetUsage)
+	return router
+}
+
+func TestQuoteHandler_CreateAndListQuotes(t *testing.T) {
+	quotes := store.NewMemoryStore()
+	handler := handlers.NewQuoteHandler(&stubResultGenerator{result: &client.GenerationResult{
+		Text:   "Hope is a waking dream.",
+		Source: "stub",
+		Model:  "stub-model",
+		Usage:  client.Usage{PromptTokens: 8, CompletionTokens: 4, TotalTokens: 12},
+	}}, quotes)
+	router := newQuoteRouter(handler)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/quote", strings.NewReader(`{"tag":"hope"}`)))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var created handlers.QuoteResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &created))
+	stored, err := quotes.Get(context.Background(), created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Hope is a waking dream.", stored.QuoteText)
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/quotes?tag=hope", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var list struct {
+		Quotes []handlers.QuoteResponse `json:"quotes"`
+		Count  int                      `json:"count"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &list))
+	assert.Equal(t, 1, list.Count)
+	assert.Equal(t, created.ID, list.Quotes[0].ID)
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/quotes?tag=joy", nil))
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &list))
+	assert.Equal(t, 0, list.Count)
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/usage?group_by=model", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var usage handlers.UsageResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &usage))
+	assert.Equal(t, int64(1), usage.Totals.Quotes)
+	assert.Equal(t, int64(12), usage.Totals.TotalTokens)
+	require.Len(t, usage.Rows, 1)
+	assert.Equal(t, "stub-model", usage.Rows[0].Model)
+}
+
+func TestQuoteHandler_GetUpdateDeleteRestore(t *testing.T) {
+	quotes := store.NewMemoryStore()
+	quote := models.Quote{Tag: "hope", TagSource: "preset", QuoteText: "Hope is a waking dream."}
+	require.NoError(t, quotes.Create(context.Background(), &quote))
+	handler := handlers.NewQuoteHandler(nil, quotes)
+	handler.Validation = client.NewPipeline(client.PipelineConfig{MinLength: 10, MaxLength: 300})
+	router := newQuoteRouter(handler)
+	path := "/api/v1/quotes/" + quote.ID.String()
+	admin := []string{"Authorization", "Bearer secret"}
+
+	serve := func(method, target, body string, header ...string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+	errorCode := func(recorder *httptest.ResponseRecorder) string {
+		var errResp handlers.ErrorResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &errResp))
+		return errResp.Error
+	}
+
+	recorder := serve(http.MethodGet, path, "")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var response handlers.QuoteResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, "Hope is a waking dream.", response.Quote)
+
+	recorder = serve(http.MethodGet, "/api/v1/quotes/not-a-uuid", "")
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, "invalid_id", errorCode(recorder))
+
+	// Only admins may edit or delete quotes
+	recorder = serve(http.MethodPatch, path, `{"quote":"Hope is gone."}`)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	recorder = serve(http.MethodDelete, path, "")
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	recorder = serve(http.MethodPatch, path, `{"quote":" Hope is a dream with open eyes. ","author":"Aristotle","tag":"optimism"}`, admin...)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, "Hope is a dream with open eyes.", response.Quote)
+	assert.Equal(t, "Aristotle", *response.Author)
+	assert.Equal(t, "optimism", response.Tag)
+
+	recorder = serve(http.MethodPatch, path, `{}`, admin...)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	recorder = serve(http.MethodPatch, path, `{"quote":"  "}`, admin...)
+	assert.Equal(t, "invalid_quote", errorCode(recorder))
+	recorder = serve(http.MethodPatch, path, `{"quote":"Hope."}`, admin...)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, "invalid_quote", errorCode(recorder), "edited quotes meet the limits of generated ones")
+	recorder = serve(http.MethodPatch, path, `{"quote":"I'm sorry, I can't write that quote."}`, admin...)
+	assert.Equal(t, "invalid_quote", errorCode(recorder))
+
+	recorder = serve(http.MethodDelete, path, "", admin...)
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	recorder = serve(http.MethodGet, path, "")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, "quote_not_found", errorCode(recorder))
+	recorder = serve(http.MethodDelete, path, "", admin...)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	recorder = serve(http.MethodPost, path+"/restore", "")
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	recorder = serve(http.MethodPost, path+"/restore", "", "Authorization", "Bearer wrong")
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	recorder = serve(http.MethodPost, path+"/restore", "", admin...)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, "Hope is a dream with open eyes.", response.Quote)
+
+	recorder = serve(http.MethodGet, path, "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	recorder = serve(http.MethodPost, "/api/v1/quotes/"+uuid.NewString()+"/restore", "", admin...)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestRequireAdmin_DisabledWithoutToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/restore", handlers.RequireAdmin(""), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/restore", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	router.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+}
@@ -0,0 +1,80 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Adeel56/quotebox/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenRouterClient_StreamQuote(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request client.ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.True(t, request.Stream)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": OPENROUTER PROCESSING\n\n")
+		for _, token := range []string{"Hope ", "is a ", "quiet lantern."} {
+			fmt.Fprintf(w, "data: {\"model\": \"openai/gpt-4o-mini\", \"choices\": [{\"delta\": {\"content\": %q}}]}\n\n", token)
+		}
+		fmt.Fprint(w, "data: {\"choices\": [], \"usage\": {\"prompt_tokens\": 40, \"completion_tokens\": 6, \"total_tokens\": 46}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	t.Setenv("OPENROUTER_BASE_URL", server.URL)
+
+	var tokens []string
+	c := client.NewOpenRouterClient()
+	result, err := c.StreamQuote(context.Background(), "hope", client.GenerateOptions{}, func(token string) error {
+		tokens = append(tokens, token)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Hope ", "is a ", "quiet lantern."}, tokens)
+	assert.Equal(t, "Hope is a quiet lantern.", result.Text)
+	assert.Equal(t, "openai/gpt-4o-mini", result.Model)
+	assert.Equal(t, 46, result.Usage.TotalTokens)
+}
+
+func TestStreamQuote_NonStreamingGenerator(t *testing.T) {
+	gen := &stubGenerator{name: "stub", text: "Delivered in one piece.", available: true}
+
+	var tokens []string
+	result, err := client.StreamQuote(context.Background(), gen, "joy", client.GenerateOptions{}, func(token string) error {
+		tokens = append(tokens, token)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Delivered in one piece."}, tokens)
+	assert.Equal(t, "Delivered in one piece.", result.Text)
+}
+
+func TestFallbackGenerator_StreamQuote(t *testing.T) {
+	resetProviderMetrics()
+
+	chain := client.NewFallbackGenerator(
+		&stubGenerator{name: "primary", err: fmt.Errorf("boom"), available: true},
+		&stubGenerator{name: "secondary", text: "Streamed by the fallback.", available: true},
+	)
+
+	var tokens []string
+	result, err := chain.StreamQuote(context.Background(), "joy", client.GenerateOptions{}, func(token string) error {
+		tokens = append(tokens, token)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "secondary", result.Source)
+	assert.Equal(t, []string{"Streamed by the fallback."}, tokens)
+}
@@ -0,0 +1,84 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Adeel56/quotebox/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStructuredQuote(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		quote   string
+		wantErr bool
+	}{
+		{"Plain JSON", `{"quote": "Wisdom begins in wonder.", "author": "Socrates", "language": "en"}`, "Wisdom begins in wonder.", false},
+		{"Code fence", "```json\n{\"quote\": \"Wisdom begins in wonder.\", \"author\": \"original\"}\n```", "Wisdom begins in wonder.", false},
+		{"Plain text", "Wisdom begins in wonder.", "", true},
+		{"Missing quote", `{"author": "Socrates"}`, "", true},
+		{"Invalid language", `{"quote": "Wisdom begins in wonder.", "language": "English"}`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			structured, err := client.ParseStructuredQuote(tt.content)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.quote, structured.Quote)
+		})
+	}
+}
+
+func TestOpenRouterClient_StructuredOutput(t *testing.T) {
+	content := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request client.ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		require.NotNil(t, request.ResponseFormat)
+		assert.Equal(t, client.ResponseFormatJSONSchema, request.ResponseFormat.Type)
+
+		json.NewEncoder(w).Encode(client.ChatCompletionResponse{
+			Choices: []client.Choice{{Message: client.Message{Role: "assistant", Content: content}}},
+		})
+	}))
+	defer server.Close()
+
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	t.Setenv("OPENROUTER_BASE_URL", server.URL)
+	t.Setenv("OPENROUTER_RESPONSE_FORMAT", client.ResponseFormatJSONSchema)
+	c := client.NewOpenRouterClient()
+
+	// Structured fields are stored when the model honours the format
+	content = `{"quote": "Courage is grace under pressure.", "author": "Ernest Hemingway", "language": "en", "explanation": "On staying composed."}`
+	result, err := c.GenerateQuote(context.Background(), "confidence", client.GenerateOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "Courage is grace under pressure.", result.Text)
+	require.NotNil(t, result.Author)
+	assert.Equal(t, "Ernest Hemingway", *result.Author)
+	require.NotNil(t, result.Language)
+	assert.Equal(t, "en", *result.Language)
+	require.NotNil(t, result.Explanation)
+
+	// Original quotes have no author
+	content = `{"quote": "Every sunrise is a quiet promise.", "author": "original", "language": "en"}`
+	result, err = c.GenerateQuote(context.Background(), "hope", client.GenerateOptions{})
+	require.NoError(t, err)
+	assert.Nil(t, result.Author)
+
+	// Plain text is used as is when the model ignores the format
+	content = "Every sunrise is a quiet promise."
+	result, err = c.GenerateQuote(context.Background(), "hope", client.GenerateOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "Every sunrise is a quiet promise.", result.Text)
+	assert.Nil(t, result.Language)
+}